@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the LLM response cache",
+	Long: `Manage the on-disk cache of LLM responses. Repeated requests with the
+same provider, model, options and prompt are answered from the cache.
+Use --no-cache on a command to bypass it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheStatusCmd.Run(cmd, args)
+	},
+}
+
+var cacheStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the cache location, size and limits",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadConfig()
+		cache, err := responseCache(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		stats, err := cache.Stats()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("LLM Response Cache:")
+		fmt.Printf("  Enabled:   %t\n", cfg.Cache.Enabled)
+		fmt.Printf("  Directory: %s\n", cache.Dir())
+		fmt.Printf("  Entries:   %d (%.1f MB)\n", stats.Entries, float64(stats.Bytes)/(1024*1024))
+		fmt.Printf("  TTL:       %s\n", limitOr(cfg.Cache.TTLHours, "h", "never expires"))
+		fmt.Printf("  Max size:  %s\n", limitOr(cfg.Cache.MaxSizeMB, " MB", "unlimited"))
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached response",
+	Run: func(cmd *cobra.Command, args []string) {
+		cache, err := responseCache(loadConfig())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		removed, err := cache.Clear()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Removed %d cached responses from %s\n", removed, cache.Dir())
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatusCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+// limitOr formats a configured limit, or none when it is disabled.
+func limitOr(n int, unit, none string) string {
+	if n <= 0 {
+		return none
+	}
+	return fmt.Sprintf("%d%s", n, unit)
+}
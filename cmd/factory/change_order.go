@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/modes"
+)
+
+var changeOrderCmd = &cobra.Command{
+	Use:   "change-order",
+	Short: "Start CHANGE_ORDER mode (track drift)",
+	Long: `Detect drift between a specification and the codebase.
+
+Appends the report to change_order.md in the contracts directory, then prints
+the detected changes as JSON to stdout. Progress goes to stderr.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, _ := cmd.Flags().GetString("spec")
+		path, _ := cmd.Flags().GetString("path")
+
+		if spec == "" {
+			fmt.Fprintln(os.Stderr, "Error: --spec is required")
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		cfg := loadConfig()
+		contractsDir := dirFromFlags(cmd, "contracts-dir", cfg.Paths.SpecsDir)
+		provider, settings, err := modeProviderFromFlags(ctx, cmd, cfg, modes.ModeChangeOrder)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		co := modes.NewChangeOrderMode(provider, contractsDir)
+		co.SetStreamHandler(streamHandlerFromFlags(cmd))
+		co.SetSettings(settings)
+		co.SetSpecFile(spec)
+		co.SetCodebasePath(path)
+
+		fmt.Fprintf(os.Stderr, "Detecting drift in %s against %s...\n", path, spec)
+		result, err := co.DetectDrift(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Found %d changes\n", len(result.Changes))
+		reportGeneration(result.GeneratedBy)
+
+		reportPath, err := co.SaveChangeOrder()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Change order saved to %s\n", reportPath)
+
+		if err := printJSON(map[string]interface{}{
+			"spec_file":     result.SpecFile,
+			"codebase_path": result.CodebasePath,
+			"changes":       result.Changes,
+			"report_path":   reportPath,
+			"generated_by":  result.GeneratedBy,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	changeOrderCmd.Flags().String("spec", "", "Specification file to compare against")
+	changeOrderCmd.Flags().String("path", ".", "Path to codebase")
+	changeOrderCmd.Flags().String("contracts-dir", "", "Directory containing change_order.md (default: paths.specs_dir)")
+	addProviderFlags(changeOrderCmd)
+	addCacheFlag(changeOrderCmd)
+	addStreamFlag(changeOrderCmd)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and edit ~/.factory/config.toml",
+	Long: `Read and edit ~/.factory/config.toml using dotted key paths such as
+llm.model or paths.reports_dir. Unknown keys and values of the wrong type
+are rejected.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f := openConfigFile()
+		cfg, err := f.Config()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		v, err := cfg.Get(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(v)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a key",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		f := openConfigFile()
+		if err := f.Set(args[0], args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := f.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a key so its default applies",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f := openConfigFile()
+		removed, err := f.Unset(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !removed {
+			fmt.Fprintf(os.Stderr, "%s is not set\n", args[0])
+			return
+		}
+		if err := f.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all keys with their effective values",
+	Run: func(cmd *cobra.Command, args []string) {
+		showOrigin, _ := cmd.Flags().GetBool("show-origin")
+
+		f := openConfigFile()
+		cfg, err := f.Config()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		for _, key := range config.Keys() {
+			v, _ := cfg.Get(key.Key)
+			if showOrigin {
+				origin := "default"
+				if f.IsSet(key.Key) {
+					origin = "file:" + f.Path
+				}
+				fmt.Printf("%s\t", origin)
+			}
+			fmt.Printf("%s=%s\n", key.Key, config.FormatValue(v))
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for unknown keys and invalid values",
+	Run: func(cmd *cobra.Command, args []string) {
+		f := openConfigFile()
+		errs := f.Validate()
+		if len(errs) == 0 {
+			fmt.Printf("✓ %s is valid\n", f.Path)
+			return
+		}
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		}
+		os.Exit(1)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	configListCmd.Flags().Bool("show-origin", false, "Show whether each value comes from the file or the defaults")
+}
+
+// openConfigFile opens the global config file or exits on parse errors.
+func openConfigFile() *config.File {
+	f, err := config.OpenGlobal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return f
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/doctor"
+	"github.com/ssdajoker/Code-Factory/internal/store"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the Factory environment",
+	Long: `Check the secret store, configuration, LLM providers, GitHub
+connection and output directories, printing pass/warn/fail per check with
+a remediation hint. Exits 1 if any check fails. Use --json to attach the
+output to a support ticket.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		cfg := loadConfig()
+		contractsDir := dirFromFlags(cmd, "contracts-dir", cfg.Paths.SpecsDir)
+		reportsDir := dirFromFlags(cmd, "reports-dir", cfg.Paths.ReportsDir)
+		ollama := valueOr(ollamaURL(cfg), "http://localhost:11434")
+
+		results := []doctor.Result{
+			doctor.CheckKeyring(),
+		}
+		if dir, err := store.SecretsDir(); err == nil {
+			results = append(results, doctor.CheckSecretsDir(dir))
+		}
+		results = append(results, doctor.CheckConfig())
+		results = append(results, doctor.CheckOllama(context.Background(), ollama))
+		token, _, tokenErr := githubToken()
+		results = append(results, doctor.CheckGitHub(token, tokenErr))
+		results = append(results, doctor.CheckWritable("contracts_dir", contractsDir))
+		results = append(results, doctor.CheckWritable("reports_dir", reportsDir))
+
+		if asJSON {
+			if err := printJSON(map[string]interface{}{
+				"version": version,
+				"commit":  commit,
+				"os":      runtime.GOOS,
+				"arch":    runtime.GOARCH,
+				"go":      runtime.Version(),
+				"checks":  results,
+				"failed":  doctor.Failed(results),
+			}); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Printf("Factory v%s (%s/%s)\n\n", version, runtime.GOOS, runtime.GOARCH)
+			icons := map[doctor.Status]string{
+				doctor.StatusPass: "✓",
+				doctor.StatusWarn: "!",
+				doctor.StatusFail: "✗",
+			}
+			for _, r := range results {
+				fmt.Printf("  %s %-14s %s\n", icons[r.Status], r.Name, r.Message)
+				if r.Hint != "" {
+					fmt.Printf("    → %s\n", r.Hint)
+				}
+			}
+		}
+
+		if doctor.Failed(results) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	doctorCmd.Flags().Bool("json", false, "Output results as JSON")
+	doctorCmd.Flags().String("contracts-dir", "", "Contracts directory to check (default: paths.specs_dir)")
+	doctorCmd.Flags().String("reports-dir", "", "Reports directory to check (default: paths.reports_dir)")
+}
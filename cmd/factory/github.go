@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/github"
+	"github.com/ssdajoker/Code-Factory/internal/store"
+)
+
+// githubTokenEnv overrides the stored GitHub token when set.
+const githubTokenEnv = "FACTORY_GITHUB_TOKEN"
+
+var githubCmd = &cobra.Command{
+	Use:   "github",
+	Short: "GitHub integration",
+	Run: func(cmd *cobra.Command, args []string) {
+		login, _ := cmd.Flags().GetBool("login")
+		status, _ := cmd.Flags().GetBool("status")
+
+		if login {
+			githubLoginCmd.Run(cmd, args)
+			return
+		}
+		if status {
+			githubStatusCmd.Run(cmd, args)
+			return
+		}
+		fmt.Println("GitHub integration commands:")
+		fmt.Println("  factory github login    Authenticate with GitHub")
+		fmt.Println("  factory github status   Show connection status")
+		fmt.Println("  factory github logout   Remove the stored token")
+	},
+}
+
+var githubLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Authenticate with GitHub using the device flow",
+	Run: func(cmd *cobra.Command, args []string) {
+		clientID, _ := cmd.Flags().GetString("client-id")
+		noBrowser, _ := cmd.Flags().GetBool("no-browser")
+
+		if clientID == "" {
+			clientID = loadConfig().GitHub.ClientID
+		}
+		if clientID == "" {
+			clientID = os.Getenv("FACTORY_GITHUB_CLIENT_ID")
+		}
+		if clientID == "" {
+			fmt.Fprintln(os.Stderr, "Error: GitHub OAuth client ID not configured")
+			fmt.Fprintln(os.Stderr, "Set github.client_id in ~/.factory/config.toml, FACTORY_GITHUB_CLIENT_ID, or pass --client-id")
+			os.Exit(1)
+		}
+
+		secrets, err := openSecretStore()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		flow := github.NewOAuthFlow(clientID)
+		code, err := flow.InitiateDeviceFlow()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("🔐 GitHub Device Authorization")
+		fmt.Println()
+		fmt.Printf("  Visit:      %s\n", code.VerificationURI)
+		fmt.Printf("  Enter code: %s\n", code.UserCode)
+		fmt.Println()
+		if !noBrowser {
+			if err := github.OpenBrowser(code.VerificationURI); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: could not open browser: %v\n", err)
+			}
+		}
+		fmt.Println("Waiting for authorization...")
+
+		token, err := flow.PollForToken(code.DeviceCode, code.Interval)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := secrets.Set(store.KeyGitHubToken, token); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to store token: %v\n", err)
+			os.Exit(1)
+		}
+
+		user, err := github.NewClient(token).GetUser()
+		if err != nil {
+			fmt.Println("✅ Authenticated with GitHub")
+			return
+		}
+		fmt.Printf("✅ Authenticated with GitHub as %s\n", user.Login)
+		if secrets.UsingFallback() {
+			fmt.Println("   Token stored in encrypted file (keyring unavailable)")
+		}
+	},
+}
+
+var githubStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show GitHub connection status",
+	Run: func(cmd *cobra.Command, args []string) {
+		token, source, err := githubToken()
+		if err != nil {
+			if errors.Is(err, store.ErrSecretNotFound) {
+				fmt.Println("GitHub connection status: Not connected")
+				fmt.Println("Run: factory github login")
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		perms, err := github.NewAppChecker(github.NewClient(token)).CheckPermissions()
+		if err != nil {
+			fmt.Printf("GitHub connection status: Token invalid (%s)\n", source)
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("GitHub connection status: Connected")
+		fmt.Printf("  User:   %s\n", perms.Username)
+		fmt.Printf("  Token:  %s\n", source)
+		fmt.Printf("  Scopes: %s\n", valueOr(strings.Join(perms.Scopes, ", "), "unknown"))
+		fmt.Printf("  Read:   %v\n", perms.CanReadRepos)
+		fmt.Printf("  Write:  %v\n", perms.CanWriteRepos)
+	},
+}
+
+var githubLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the stored GitHub token",
+	Run: func(cmd *cobra.Command, args []string) {
+		secrets, err := openSecretStore()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := secrets.Delete(store.KeyGitHubToken); err != nil {
+			if errors.Is(err, store.ErrSecretNotFound) {
+				fmt.Println("Not logged in")
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Logged out of GitHub")
+		if os.Getenv(githubTokenEnv) != "" {
+			fmt.Printf("Note: %s is still set in the environment\n", githubTokenEnv)
+		}
+	},
+}
+
+func init() {
+	githubCmd.AddCommand(githubLoginCmd)
+	githubCmd.AddCommand(githubStatusCmd)
+	githubCmd.AddCommand(githubLogoutCmd)
+
+	githubCmd.Flags().Bool("login", false, "Authenticate with GitHub")
+	githubCmd.Flags().Bool("status", false, "Show GitHub connection status")
+	githubCmd.Flags().String("client-id", "", "GitHub OAuth app client ID")
+	githubCmd.Flags().Bool("no-browser", false, "Do not open the verification URL in a browser")
+
+	githubLoginCmd.Flags().String("client-id", "", "GitHub OAuth app client ID")
+	githubLoginCmd.Flags().Bool("no-browser", false, "Do not open the verification URL in a browser")
+}
+
+// githubToken returns the GitHub token and where it came from.
+func githubToken() (token, source string, err error) {
+	if token := os.Getenv(githubTokenEnv); token != "" {
+		return token, githubTokenEnv, nil
+	}
+
+	secrets, err := openSecretStore()
+	if err != nil {
+		return "", "", err
+	}
+	token, err = secrets.Get(store.KeyGitHubToken)
+	if err != nil {
+		return "", "", err
+	}
+	if secrets.UsingFallback() {
+		return token, "encrypted file", nil
+	}
+	return token, "keyring", nil
+}
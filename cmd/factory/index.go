@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/config"
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/modes"
+)
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Embed the code so modes can retrieve the parts relevant to a spec",
+	Long: `Embed the code under --path in chunks and store the vectors in
+.factory/cache/vectors.json. Only new or changed chunks are embedded again.
+With --query, print the chunks most relevant to the query.
+
+The embedding model is --embed-model, llm.embed_model, or the provider's
+default. Anthropic cannot embed text; use Ollama, OpenAI, OpenRouter or an
+OpenAI-compatible server.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		paths, _ := cmd.Flags().GetStringSlice("path")
+		query, _ := cmd.Flags().GetString("query")
+		top, _ := cmd.Flags().GetInt("top")
+
+		ctx := context.Background()
+		cfg := loadConfig()
+		provider, err := providerFromFlags(ctx, cmd, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if provider == nil {
+			fmt.Fprintln(os.Stderr, "Error: indexing needs an LLM provider that can embed text")
+			os.Exit(1)
+		}
+
+		index, err := codeIndex(cmd, cfg, provider)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		embedded, err := index.Update(ctx, paths)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Indexed %v (%d chunks embedded) in %s\n", paths, embedded, config.VectorStorePath("."))
+
+		if query == "" {
+			return
+		}
+		matches, err := index.Search(ctx, query, top)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, m := range matches {
+			fmt.Printf("--- %s (%.3f) ---\n%s\n", m.ID, m.Score, m.Text)
+		}
+	},
+}
+
+func init() {
+	indexCmd.Flags().StringSlice("path", []string{"."}, "Code paths to index (repeatable)")
+	indexCmd.Flags().String("query", "", "Print the chunks most relevant to this text")
+	indexCmd.Flags().Int("top", 5, "Number of chunks to print for --query")
+	addProviderFlags(indexCmd)
+	addEmbedModelFlag(indexCmd)
+}
+
+// addEmbedModelFlag registers the --embed-model flag on cmd.
+func addEmbedModelFlag(cmd *cobra.Command) {
+	cmd.Flags().String("embed-model", "", "Embedding model (defaults to llm.embed_model, then the provider's default)")
+}
+
+// codeIndex opens the project's code index, embedding with provider and
+// the model selected on cmd.
+func codeIndex(cmd *cobra.Command, cfg *config.Config, provider llm.Provider) (*modes.CodeIndex, error) {
+	model, _ := cmd.Flags().GetString("embed-model")
+	if model == "" {
+		model = cfg.LLM.EmbedModel
+	}
+	store, err := llm.OpenVectorStore(config.VectorStorePath("."))
+	if err != nil {
+		return nil, err
+	}
+	return modes.NewCodeIndex(provider, model, store), nil
+}
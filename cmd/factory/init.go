@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/config"
+	"github.com/ssdajoker/Code-Factory/internal/project"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Initialize Factory in current project",
+	Long: `Initialize Factory in the current project.
+
+Detects the project language and git remote, probes for LLM providers,
+creates the .factory/ layout with specs and reports directories, and writes
+.factory/config.toml. Use --quick to accept all defaults without prompting.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		quick, _ := cmd.Flags().GetBool("quick")
+		force, _ := cmd.Flags().GetBool("force")
+		name, _ := cmd.Flags().GetString("name")
+
+		fmt.Println("🏭 Spec-Driven Software Factory")
+		fmt.Println()
+
+		// Phase 1: context detection
+		pctx, err := project.Detect(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if pctx.Initialized && !force {
+			fmt.Printf("Factory is already initialized in %s (use --force to reinitialize)\n", pctx.Root)
+			return
+		}
+
+		fmt.Println("Detecting project context...")
+		fmt.Printf("  Directory:  %s\n", pctx.Root)
+		fmt.Printf("  Language:   %s\n", valueOr(strings.Join(pctx.Languages, ", "), "unknown"))
+		switch {
+		case pctx.Repository != "":
+			fmt.Printf("  Repository: %s\n", pctx.Repository)
+		case pctx.Remote != "":
+			fmt.Printf("  Remote:     %s\n", pctx.Remote)
+		case pctx.IsGitRepo:
+			fmt.Println("  Git:        no origin remote")
+		default:
+			fmt.Println("  Git:        not a git repository")
+		}
+		fmt.Println()
+
+		in := bufio.NewReader(os.Stdin)
+		ask := func(label, def string) string {
+			if quick {
+				return def
+			}
+			return prompt(in, label, def)
+		}
+
+		global := loadConfig()
+		if name == "" {
+			name = ask("Project name", pctx.Name)
+		}
+
+		// Phase 2: LLM configuration
+		fmt.Println("Detecting LLM providers...")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		detection := newDetector(global).Detect(ctx)
+		cancel()
+		fmt.Printf("  %s\n\n", detection.Message)
+
+		providerName, model := global.LLM.Provider, global.LLM.Model
+		if detection.Available {
+			providerName = string(detection.ProviderType)
+			if len(detection.Models) > 0 && !contains(detection.Models, model) {
+				model = detection.Models[0]
+			}
+		}
+		providerName = ask("LLM provider", providerName)
+		model = ask("Model", model)
+
+		// Phase 3: project initialization
+		pcfg := config.NewProjectConfig(name, global)
+		pcfg.Project.Repository = pctx.Repository
+		pcfg.Project.Language = pctx.Language()
+		pcfg.LLM.Provider = providerName
+		pcfg.LLM.Model = model
+
+		created, err := project.Init(pctx.Root, pcfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Initializing project structure...")
+		for _, path := range created {
+			fmt.Printf("  ✓ %s\n", path)
+		}
+		fmt.Println()
+
+		if !config.Exists() {
+			global.LLM.Provider = pcfg.LLM.Provider
+			global.LLM.Model = pcfg.LLM.Model
+			if err := global.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to write global config: %v\n", err)
+			} else {
+				fmt.Println("  ✓ ~/.factory/config.toml")
+				fmt.Println()
+			}
+		}
+
+		// Phase 4: summary
+		fmt.Println("✅ Setup complete!")
+		fmt.Println()
+		fmt.Printf("  Project:      %s\n", name)
+		if pctx.Repository != "" {
+			fmt.Printf("  Repository:   %s\n", pctx.Repository)
+		}
+		fmt.Printf("  LLM Provider: %s (%s)\n", providerName, model)
+		fmt.Println()
+		fmt.Println("Next steps:")
+		fmt.Println("  factory          Start the TUI")
+		fmt.Println("  factory intake   Create your first specification")
+		fmt.Println("  factory review   Review code against specs")
+	},
+}
+
+func init() {
+	initCmd.Flags().Bool("quick", false, "Quick start with defaults")
+	initCmd.Flags().Bool("force", false, "Reinitialize an existing project")
+	initCmd.Flags().String("name", "", "Project name (defaults to the directory name)")
+}
+
+// prompt asks for a value on stdout, returning def on empty input or EOF.
+func prompt(in *bufio.Reader, label, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", label, def)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+	line, err := in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if err != nil && line == "" {
+		fmt.Println()
+		return def
+	}
+	if line == "" {
+		return def
+	}
+	return line
+}
+
+func valueOr(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/modes"
+	"github.com/ssdajoker/Code-Factory/internal/tui"
+)
+
+var intakeCmd = &cobra.Command{
+	Use:   "intake",
+	Short: "Start INTAKE mode (capture vision)",
+	Long: `Capture the project vision and generate a specification.
+
+Without --answers the interactive TUI starts. With --answers the six intake
+answers are read from a TOML, YAML or JSON file with the keys project_name,
+description, target_users, core_features, technical_constraints and
+success_criteria; the spec is generated, saved to the contracts directory
+and printed to stdout.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		answers, _ := cmd.Flags().GetString("answers")
+
+		if answers == "" {
+			fmt.Println("Starting INTAKE mode...")
+			if name != "" {
+				fmt.Printf("Project: %s\n", name)
+			}
+			cfg := loadConfig()
+			if err := tui.RunIntake(modeResolver(cfg), dirFromFlags(cmd, "contracts-dir", cfg.Paths.SpecsDir)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		data, err := modes.LoadIntakeData(answers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if name != "" {
+			data.ProjectName = name
+		}
+
+		ctx := context.Background()
+		cfg := loadConfig()
+		contractsDir := dirFromFlags(cmd, "contracts-dir", cfg.Paths.SpecsDir)
+		provider, settings, err := modeProviderFromFlags(ctx, cmd, cfg, modes.ModeIntake)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		intake := modes.NewIntakeMode(provider, contractsDir)
+		intake.SetStreamHandler(streamHandlerFromFlags(cmd))
+		intake.SetSettings(settings)
+		intake.SetData(data)
+
+		fmt.Fprintf(os.Stderr, "Generating specification for %s...\n", data.ProjectName)
+		spec, err := intake.GenerateSpec(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		reportGeneration(intake.GeneratedBy())
+
+		path, err := intake.SaveSpec()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Spec saved to %s\n", path)
+		fmt.Print(spec)
+	},
+}
+
+func init() {
+	intakeCmd.Flags().String("name", "", "Project name")
+	intakeCmd.Flags().String("answers", "", "Answers file (.toml, .yaml or .json) for non-interactive intake")
+	intakeCmd.Flags().String("contracts-dir", "", "Directory to write vision_spec.md to (default: paths.specs_dir)")
+	addProviderFlags(intakeCmd)
+	addCacheFlag(intakeCmd)
+	addStreamFlag(intakeCmd)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+)
+
+var llmCmd = &cobra.Command{
+	Use:   "llm",
+	Short: "LLM provider management",
+	Run: func(cmd *cobra.Command, args []string) {
+		setup, _ := cmd.Flags().GetBool("setup")
+		if setup {
+			llmSetupCmd.Run(cmd, args)
+			return
+		}
+		llmStatusCmd.Run(cmd, args)
+	},
+}
+
+var llmStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show reachability, models and latency for every provider",
+	Run: func(cmd *cobra.Command, args []string) {
+		noProbe, _ := cmd.Flags().GetBool("no-probe")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		cfg := loadConfig()
+		detector := newDetector(cfg)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		fmt.Println("LLM Status:")
+		fmt.Printf("  Configured: %s (model: %s)\n", cfg.LLM.Provider, valueOr(cfg.LLM.Model, "default"))
+
+		// Auto-detection picks the first available provider in priority
+		// order, the same order DetectAll reports them in
+		results := detector.DetectAll(ctx)
+		selected := ""
+		for _, result := range results {
+			if result.Available {
+				selected = result.ProviderName
+				break
+			}
+		}
+		if selected != "" {
+			fmt.Printf("  Auto-detect selects: %s\n", selected)
+		} else {
+			fmt.Println("  Auto-detect selects: none (install Ollama or configure an API key)")
+		}
+		fmt.Println()
+
+		var ledger *llm.Ledger
+		if !noProbe {
+			ledger = usageLedger()
+		}
+		for _, result := range results {
+			if !result.Available {
+				fmt.Printf("  ✗ %-10s %s\n", result.ProviderName, result.Message)
+				continue
+			}
+			fmt.Printf("  ✓ %-10s %s\n", result.ProviderName, result.Message)
+
+			model := defaultModelFor(result, cfg.LLM.Provider, cfg.LLM.Model)
+			if len(result.Models) > 0 {
+				fmt.Printf("      Models:  %s\n", modelSummary(result.Models))
+			}
+			fmt.Printf("      Default: %s\n", valueOr(model, "provider default"))
+
+			if noProbe {
+				continue
+			}
+			provider, err := detector.ProviderFor(result, model)
+			if err != nil {
+				fmt.Printf("      Latency: error: %v\n", err)
+				continue
+			}
+			provider = llm.NewMeteredProvider(provider, model, budget(cfg), ledger)
+			latency, err := llm.MeasureLatency(ctx, provider, model)
+			if err != nil {
+				fmt.Printf("      Latency: error: %v\n", err)
+				continue
+			}
+			fmt.Printf("      Latency: %s\n", latency.Round(time.Millisecond))
+		}
+	},
+}
+
+var llmTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test prompt to a provider",
+	Run: func(cmd *cobra.Command, args []string) {
+		promptText, _ := cmd.Flags().GetString("prompt")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		provider, err := providerFromFlags(ctx, cmd, loadConfig())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if provider == nil {
+			fmt.Fprintln(os.Stderr, "Error: no LLM provider available")
+			os.Exit(1)
+		}
+
+		opts := llm.DefaultOptions()
+		if model, _ := cmd.Flags().GetString("model"); model != "" {
+			opts.Model = model
+		}
+		opts.MaxTokens = 256
+
+		fmt.Fprintf(os.Stderr, "Prompt: %s\n", promptText)
+		start := time.Now()
+		response, err := llm.Complete(ctx, provider, promptText, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Response from %s in %s:\n", provider.Name(), time.Since(start).Round(time.Millisecond))
+		fmt.Println(strings.TrimSpace(response))
+	},
+}
+
+var llmModelsCmd = &cobra.Command{
+	Use:   "models",
+	Short: "List a provider's models with context length, pricing and capabilities",
+	Run: func(cmd *cobra.Command, args []string) {
+		sortBy, _ := cmd.Flags().GetString("sort")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if sortBy != "name" && sortBy != "price" {
+			fmt.Fprintf(os.Stderr, "Error: unknown sort %q (want name or price)\n", sortBy)
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		cfg := loadConfig()
+		provider, err := providerFromFlags(ctx, cmd, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if provider == nil {
+			fmt.Fprintln(os.Stderr, "Error: no LLM provider available")
+			os.Exit(1)
+		}
+
+		cache := modelCache(cfg)
+		if refresh, _ := cmd.Flags().GetBool("refresh"); refresh {
+			cache.Forget(provider)
+		}
+		models, err := llm.DiscoverModels(ctx, provider, cache)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if sortBy == "price" {
+			llm.SortModelsByPrice(models)
+		} else {
+			sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODEL\tCONTEXT\tPROMPT $/1M\tCOMPLETION $/1M\tCAPABILITIES")
+		for _, m := range models {
+			// Providers that report metadata report real zero prices for free models
+			known := m.ContextLength > 0
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Name, contextLength(m.ContextLength),
+				pricePerMillion(m.Pricing.Prompt, known), pricePerMillion(m.Pricing.Completion, known), capabilities(m))
+		}
+		w.Flush()
+	},
+}
+
+var llmUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show today's LLM token usage and spending against the budget",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := loadConfig()
+		ledger := usageLedger()
+		if ledger == nil {
+			os.Exit(1)
+		}
+		today, err := ledger.Today()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("LLM Usage Today:")
+		fmt.Printf("  Requests: %d\n", today.Requests)
+		fmt.Printf("  Tokens:   %d (%d prompt, %d completion)\n", today.TotalTokens(), today.PromptTokens, today.CompletionTokens)
+		fmt.Printf("  Cost:     $%.4f\n", today.Cost)
+		fmt.Printf("  Budget:   %s per run, %s per day\n", budgetOr(cfg.Budget.PerRunUSD), budgetOr(cfg.Budget.PerDayUSD))
+	},
+}
+
+var llmSetupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Show how to configure an LLM provider",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("LLM Setup:")
+		fmt.Println("  1. Install Ollama: https://ollama.ai")
+		fmt.Println("  2. Or set OPENAI_API_KEY / ANTHROPIC_API_KEY / OPENROUTER_API_KEY")
+	},
+}
+
+func init() {
+	llmCmd.AddCommand(llmStatusCmd)
+	llmCmd.AddCommand(llmTestCmd)
+	llmCmd.AddCommand(llmModelsCmd)
+	llmCmd.AddCommand(llmUsageCmd)
+	llmCmd.AddCommand(llmSetupCmd)
+
+	llmCmd.Flags().Bool("status", false, "Show LLM status")
+	llmCmd.Flags().Bool("setup", false, "Setup LLM provider")
+	llmCmd.Flags().Bool("no-probe", false, "Skip latency probing")
+	llmCmd.Flags().Duration("timeout", 30*time.Second, "Overall timeout for detection and probes")
+
+	llmStatusCmd.Flags().Bool("no-probe", false, "Skip latency probing")
+	llmStatusCmd.Flags().Duration("timeout", 30*time.Second, "Overall timeout for detection and probes")
+
+	llmTestCmd.Flags().String("prompt", "Say hello in one short sentence.", "Prompt to send")
+	llmTestCmd.Flags().Duration("timeout", 60*time.Second, "Request timeout")
+	addProviderFlags(llmTestCmd)
+
+	llmModelsCmd.Flags().String("sort", "name", "Sort order: name or price")
+	llmModelsCmd.Flags().Duration("timeout", 30*time.Second, "Request timeout")
+	llmModelsCmd.Flags().Bool("refresh", false, "Query the provider instead of using the cached model list")
+	addProviderFlags(llmModelsCmd)
+}
+
+// budgetOr formats a spending limit, or "no limit" when it is disabled.
+func budgetOr(usd float64) string {
+	if usd <= 0 {
+		return "no limit"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
+
+// defaultModelFor returns the configured model when the result is for the
+// configured provider, otherwise the first detected model.
+func defaultModelFor(result llm.DetectionResult, configuredProvider, configuredModel string) string {
+	if string(result.ProviderType) == configuredProvider && configuredModel != "" {
+		return configuredModel
+	}
+	if len(result.Models) > 0 {
+		return result.Models[0]
+	}
+	return ""
+}
+
+// capabilities formats a model's capabilities, or "-" when unknown.
+func capabilities(m llm.Model) string {
+	if len(m.Capabilities) == 0 {
+		return "-"
+	}
+	names := make([]string, len(m.Capabilities))
+	for i, c := range m.Capabilities {
+		names[i] = string(c)
+	}
+	return strings.Join(names, ",")
+}
+
+// modelSummary lists the first few models and counts the rest.
+func modelSummary(models []string) string {
+	const shown = 5
+	if len(models) <= shown {
+		return strings.Join(models, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(models[:shown], ", "), len(models)-shown)
+}
+
+// contextLength formats a context window size, or "-" when unknown.
+func contextLength(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
+
+// pricePerMillion formats a per-token price as dollars per million tokens,
+// or "-" when the price is unknown.
+func pricePerMillion(perToken float64, known bool) string {
+	if perToken == 0 && !known {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", perToken*1e6)
+}
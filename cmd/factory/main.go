@@ -25,7 +25,8 @@ Factory helps you capture project vision, generate specifications,
 and maintain alignment between code and contracts.`,
         Run: func(cmd *cobra.Command, args []string) {
                 // No subcommand: launch TUI
-                if err := tui.Run(); err != nil {
+                cfg := loadConfig()
+                if err := tui.Run(modeResolver(cfg), cfg.Paths.SpecsDir, cfg.Paths.ReportsDir); err != nil {
                         fmt.Fprintf(os.Stderr, "Error: %v\n", err)
                         os.Exit(1)
                 }
@@ -40,118 +41,14 @@ var versionCmd = &cobra.Command{
         },
 }
 
-var initCmd = &cobra.Command{
-        Use:   "init",
-        Short: "Initialize Factory in current project",
-        Run: func(cmd *cobra.Command, args []string) {
-                quick, _ := cmd.Flags().GetBool("quick")
-                fmt.Println("Running factory init...")
-                if quick {
-                        fmt.Println("Using quick start mode")
-                }
-                fmt.Println("(Implementation coming soon)")
-        },
-}
-
-var intakeCmd = &cobra.Command{
-        Use:   "intake",
-        Short: "Start INTAKE mode (capture vision)",
-        Run: func(cmd *cobra.Command, args []string) {
-                name, _ := cmd.Flags().GetString("name")
-                fmt.Println("Starting INTAKE mode...")
-                if name != "" {
-                        fmt.Printf("Project: %s\n", name)
-                }
-                if err := tui.RunIntake(); err != nil {
-                        fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-                        os.Exit(1)
-                }
-        },
-}
-
-var reviewCmd = &cobra.Command{
-        Use:   "review",
-        Short: "Start REVIEW mode (check code against specs)",
-        Run: func(cmd *cobra.Command, args []string) {
-                spec, _ := cmd.Flags().GetString("spec")
-                fmt.Println("Starting REVIEW mode...")
-                if spec != "" {
-                        fmt.Printf("Spec: %s\n", spec)
-                }
-                fmt.Println("(Implementation coming soon)")
-        },
-}
-
-var rescueCmd = &cobra.Command{
-        Use:   "rescue",
-        Short: "Start RESCUE mode (reverse-engineer codebase)",
-        Run: func(cmd *cobra.Command, args []string) {
-                path, _ := cmd.Flags().GetString("path")
-                fmt.Println("Starting RESCUE mode...")
-                fmt.Printf("Path: %s\n", path)
-                fmt.Println("(Implementation coming soon)")
-        },
-}
-
-var changeOrderCmd = &cobra.Command{
-        Use:   "change-order",
-        Short: "Start CHANGE_ORDER mode (track drift)",
-        Run: func(cmd *cobra.Command, args []string) {
-                fmt.Println("Starting CHANGE_ORDER mode...")
-                fmt.Println("(Implementation coming soon)")
-        },
-}
-
-var githubCmd = &cobra.Command{
-        Use:   "github",
-        Short: "GitHub integration",
-        Run: func(cmd *cobra.Command, args []string) {
-                login, _ := cmd.Flags().GetBool("login")
-                status, _ := cmd.Flags().GetBool("status")
-
-                if login {
-                        fmt.Println("Starting GitHub OAuth flow...")
-                        fmt.Println("(Implementation coming soon)")
-                        return
-                }
-                if status {
-                        fmt.Println("GitHub connection status: Not connected")
-                        return
-                }
-                fmt.Println("GitHub integration commands:")
-                fmt.Println("  factory github --login   Authenticate with GitHub")
-                fmt.Println("  factory github --status  Show connection status")
-        },
-}
-
-var llmCmd = &cobra.Command{
-        Use:   "llm",
-        Short: "LLM provider management",
-        Run: func(cmd *cobra.Command, args []string) {
-                status, _ := cmd.Flags().GetBool("status")
-                setup, _ := cmd.Flags().GetBool("setup")
-
-                if status || (!status && !setup) {
-                        fmt.Println("LLM Status:")
-                        fmt.Println("  Checking Ollama... (localhost:11434)")
-                        fmt.Println("  Checking API keys...")
-                        fmt.Println("  (Full implementation coming soon)")
-                        return
-                }
-                if setup {
-                        fmt.Println("LLM Setup:")
-                        fmt.Println("  1. Install Ollama: https://ollama.ai")
-                        fmt.Println("  2. Or set OPENAI_API_KEY / ANTHROPIC_API_KEY")
-                }
-        },
-}
-
 var webCmd = &cobra.Command{
         Use:   "web",
         Short: "Start web UI server",
         Run: func(cmd *cobra.Command, args []string) {
                 port, _ := cmd.Flags().GetInt("port")
-                server := web.NewServer(port, "contracts", "reports")
+                cfg := loadConfig()
+                server := web.NewServer(port, cfg.Paths.SpecsDir, cfg.Paths.ReportsDir)
+                server.SetResolver(modeResolver(cfg))
                 if err := server.Start(); err != nil {
                         fmt.Fprintf(os.Stderr, "Error: %v\n", err)
                         os.Exit(1)
@@ -169,17 +66,14 @@ func init() {
         rootCmd.AddCommand(changeOrderCmd)
         rootCmd.AddCommand(githubCmd)
         rootCmd.AddCommand(llmCmd)
+        rootCmd.AddCommand(configCmd)
+        rootCmd.AddCommand(doctorCmd)
+        rootCmd.AddCommand(cacheCmd)
+        rootCmd.AddCommand(indexCmd)
+        rootCmd.AddCommand(templatesCmd)
         rootCmd.AddCommand(webCmd)
 
         // Add flags
-        initCmd.Flags().Bool("quick", false, "Quick start with defaults")
-        intakeCmd.Flags().String("name", "", "Project name")
-        reviewCmd.Flags().String("spec", "", "Specification file to review against")
-        rescueCmd.Flags().String("path", ".", "Path to codebase")
-        githubCmd.Flags().Bool("login", false, "Authenticate with GitHub")
-        githubCmd.Flags().Bool("status", false, "Show GitHub connection status")
-        llmCmd.Flags().Bool("status", false, "Show LLM status")
-        llmCmd.Flags().Bool("setup", false, "Setup LLM provider")
         webCmd.Flags().Int("port", 3333, "Port for web server")
 }
 
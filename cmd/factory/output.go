@@ -0,0 +1,13 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// printJSON writes v to stdout as indented JSON.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
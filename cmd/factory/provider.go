@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/config"
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/modes"
+)
+
+// detectProvider returns the best available LLM provider, or nil when none
+// is reachable so that modes fall back to their template output.
+func detectProvider(ctx context.Context, cfg *config.Config) (llm.Provider, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	provider, err := newDetector(cfg).GetBestProvider(ctx)
+	if errors.Is(err, llm.ErrNoProvider) {
+		return nil, nil
+	}
+	return provider, err
+}
+
+// newDetector creates a detector using the configured Ollama or
+// OpenAI-compatible endpoint and API keys from the environment.
+func newDetector(cfg *config.Config) *llm.Detector {
+	d := llm.NewDetector(ollamaURL(cfg), apiKeyFor(llm.ProviderOpenAI), apiKeyFor(llm.ProviderAnthropic))
+	d.SetOpenRouterKey(apiKeyFor(llm.ProviderOpenRouter))
+	d.SetMaxAttempts(cfg.LLM.MaxAttempts)
+	d.SetModelCache(modelCache(cfg))
+	if cfg.LLM.Provider == string(llm.ProviderOpenAICompatible) {
+		d.SetOpenAICompatible(cfg.LLM.BaseURL, apiKeyFor(llm.ProviderOpenAICompatible), cfg.LLM.Headers)
+	}
+	return d
+}
+
+// resolveProvider returns the provider selected by name. "auto" uses the
+// configured fallback chain or runs detection, "none" disables the LLM, a
+// comma-separated list builds a fallback chain, anything else is built
+// directly.
+func resolveProvider(ctx context.Context, cfg *config.Config, name, model string) (llm.Provider, error) {
+	switch name {
+	case "", "auto":
+		if len(cfg.LLM.Fallback) > 0 {
+			return fallbackProvider(ctx, cfg, cfg.LLM.Fallback, model)
+		}
+		provider, err := detectProvider(ctx, cfg)
+		if err != nil || provider == nil {
+			return provider, err
+		}
+		if err := validateModel(ctx, cfg, provider, model); err != nil {
+			return nil, err
+		}
+		return provider, nil
+	case "none":
+		return nil, nil
+	}
+	if strings.Contains(name, ",") {
+		return fallbackProvider(ctx, cfg, strings.Split(name, ","), model)
+	}
+
+	providerType := llm.ProviderType(name)
+	baseURL := ""
+	var headers map[string]string
+	switch providerType {
+	case llm.ProviderOllama:
+		baseURL = ollamaURL(cfg)
+	case llm.ProviderOpenAICompatible:
+		baseURL = cfg.LLM.BaseURL
+		headers = cfg.LLM.Headers
+	}
+	if model == "" && name == cfg.LLM.Provider {
+		model = cfg.LLM.Model
+	}
+	provider, err := llm.NewProvider(llm.Config{
+		Type:        providerType,
+		APIKey:      apiKeyFor(providerType),
+		BaseURL:     baseURL,
+		Model:       model,
+		Headers:     headers,
+		MaxAttempts: cfg.LLM.MaxAttempts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := validateModel(ctx, cfg, provider, model); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// validateModel fails when the provider's model list does not include
+// model, so that a misspelled name is reported before any work is done.
+// Models of providers that cannot be reached are not checked.
+func validateModel(ctx context.Context, cfg *config.Config, provider llm.Provider, model string) error {
+	if model == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	models, err := llm.DiscoverModels(ctx, provider, modelCache(cfg))
+	if err != nil {
+		return nil
+	}
+	return llm.CheckModel(models, provider.Name(), model)
+}
+
+// modelCache opens the cache of provider model lists, or returns nil when
+// its location cannot be determined.
+func modelCache(cfg *config.Config) *llm.ModelCache {
+	dir, err := config.ModelCacheDir()
+	if err != nil {
+		return nil
+	}
+	return llm.NewModelCache(dir, time.Duration(cfg.Cache.ModelsTTLHours)*time.Hour)
+}
+
+// fallbackProvider builds a chain from the named providers, skipping any
+// that cannot be constructed (for example for lack of an API key) or that
+// do not serve model, when one is given.
+func fallbackProvider(ctx context.Context, cfg *config.Config, names []string, model string) (llm.Provider, error) {
+	var chain []llm.Provider
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "auto" || name == "none" || strings.Contains(name, ",") {
+			return nil, fmt.Errorf("invalid provider %q in fallback chain", name)
+		}
+		p, err := resolveProvider(ctx, cfg, name, model)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping %s in fallback chain: %v\n", name, err)
+			continue
+		}
+		chain = append(chain, p)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no usable provider in fallback chain %v", names)
+	}
+	fallback := llm.NewFallbackProvider(chain...)
+	fallback.SetTimeout(time.Duration(cfg.LLM.FallbackTimeoutSeconds) * time.Second)
+	return fallback, nil
+}
+
+// apiKeyFor returns the API key for a hosted provider from the environment.
+func apiKeyFor(providerType llm.ProviderType) string {
+	switch providerType {
+	case llm.ProviderOpenAI:
+		return os.Getenv("OPENAI_API_KEY")
+	case llm.ProviderAnthropic:
+		return os.Getenv("ANTHROPIC_API_KEY")
+	case llm.ProviderOpenRouter:
+		return os.Getenv("OPENROUTER_API_KEY")
+	case llm.ProviderOpenAICompatible:
+		return os.Getenv("OPENAI_COMPATIBLE_API_KEY")
+	}
+	return ""
+}
+
+// addProviderFlags registers the --provider and --model flags on cmd.
+func addProviderFlags(cmd *cobra.Command) {
+	cmd.Flags().String("provider", "auto", "LLM provider: auto, none, ollama, openai, anthropic, openrouter, openai-compatible, or a comma-separated fallback chain")
+	cmd.Flags().String("model", "", "Model name (defaults to the provider's default)")
+}
+
+// providerFromFlags resolves the provider selected on cmd and reports the
+// choice on stderr.
+func providerFromFlags(ctx context.Context, cmd *cobra.Command, cfg *config.Config) (llm.Provider, error) {
+	name, _ := cmd.Flags().GetString("provider")
+	model, _ := cmd.Flags().GetString("model")
+	if model != "" && isChain(cfg, name) {
+		return nil, errModelWithChain
+	}
+
+	provider, err := buildProvider(ctx, cfg, name, model, useCacheFromFlags(cmd, cfg))
+	if err != nil {
+		return nil, err
+	}
+	reportProvider(provider)
+	return provider, nil
+}
+
+// errModelWithChain reports a --model flag given for a fallback chain,
+// whose providers each serve their own models
+var errModelWithChain = errors.New("--model cannot be combined with a fallback chain; set each provider's model in the config")
+
+// isChain reports whether the provider name selects a fallback chain.
+func isChain(cfg *config.Config, name string) bool {
+	if name == "" || name == "auto" {
+		return len(cfg.LLM.Fallback) > 0
+	}
+	return strings.Contains(name, ",")
+}
+
+// modeProviderFromFlags resolves the provider and settings for mode. The
+// --provider and --model flags take precedence over the mode's section of
+// the config; a model set there applies only when --provider is not given.
+func modeProviderFromFlags(ctx context.Context, cmd *cobra.Command, cfg *config.Config, mode string) (llm.Provider, modes.Settings, error) {
+	mc := cfg.Modes.Mode(mode)
+	name, _ := cmd.Flags().GetString("provider")
+	model, _ := cmd.Flags().GetString("model")
+	if !cmd.Flags().Changed("provider") {
+		name = valueOr(mc.Provider, name)
+	}
+	if model != "" && isChain(cfg, name) {
+		return nil, modes.Settings{}, errModelWithChain
+	}
+
+	useCache := useCacheFromFlags(cmd, cfg)
+	var provider llm.Provider
+	var err error
+	if model == "" && !cmd.Flags().Changed("provider") {
+		provider, model, err = buildModeProvider(ctx, cfg, name, mc.Model, useCache)
+		if err == nil && provider != nil && mc.Model != "" && model == "" {
+			fmt.Fprintf(os.Stderr, "warning: %s does not serve %s, the model of modes.%s; using its default\n", provider.Name(), mc.Model, mode)
+		}
+	} else {
+		provider, err = buildProvider(ctx, cfg, name, model, useCache)
+	}
+	if err != nil {
+		return nil, modes.Settings{}, err
+	}
+	reportProvider(provider)
+	return provider, modeSettings(ctx, cfg, mc, provider, model), nil
+}
+
+// modeResolver resolves the provider and settings of each mode from the
+// config, for the TUI and web UI.
+func modeResolver(cfg *config.Config) modes.Resolver {
+	return func(ctx context.Context, mode string) (llm.Provider, modes.Settings, error) {
+		mc := cfg.Modes.Mode(mode)
+		provider, model, err := buildModeProvider(ctx, cfg, valueOr(mc.Provider, "auto"), mc.Model, cfg.Cache.Enabled)
+		if err != nil {
+			return nil, modes.Settings{}, err
+		}
+		return provider, modeSettings(ctx, cfg, mc, provider, model), nil
+	}
+}
+
+// buildModeProvider builds the provider named in a mode's config section
+// and returns it with the model its requests name. The section's model
+// applies to each provider of a fallback chain that serves it; a detected
+// provider that does not serve it keeps its own model, and "" is returned.
+func buildModeProvider(ctx context.Context, cfg *config.Config, name, model string, useCache bool) (llm.Provider, string, error) {
+	if model == "" || isChain(cfg, name) || (name != "" && name != "auto") {
+		provider, err := buildProvider(ctx, cfg, name, model, useCache)
+		return provider, model, err
+	}
+
+	provider, err := buildProvider(ctx, cfg, name, "", useCache)
+	if err != nil || provider == nil {
+		return provider, "", err
+	}
+	if err := validateModel(ctx, cfg, provider, model); err != nil {
+		return provider, "", nil
+	}
+	return provider, model, nil
+}
+
+// modeSettings converts a mode's config section to request settings for
+// model on provider, using the configured prompt templates and the
+// provider's context window.
+func modeSettings(ctx context.Context, cfg *config.Config, mc config.ModeConfig, provider llm.Provider, model string) modes.Settings {
+	return modes.Settings{
+		Model:         model,
+		Temperature:   mc.Temperature,
+		MaxTokens:     mc.MaxTokens,
+		Timeout:       time.Duration(mc.TimeoutSeconds) * time.Second,
+		Prompts:       promptSet(cfg),
+		ContextWindow: contextWindowFor(ctx, cfg, provider, model),
+	}
+}
+
+// buildProvider resolves the named provider and wraps it to meter usage
+// and, when useCache is set, to serve repeated requests from the response
+// cache. It returns nil when no provider is in use.
+func buildProvider(ctx context.Context, cfg *config.Config, name, model string, useCache bool) (llm.Provider, error) {
+	provider, err := resolveProvider(ctx, cfg, name, model)
+	if err != nil || provider == nil {
+		return nil, err
+	}
+
+	if model == "" && name == cfg.LLM.Provider {
+		model = cfg.LLM.Model
+	}
+	provider = llm.NewMeteredProvider(provider, model, budget(cfg), usageLedger())
+
+	if useCache {
+		cache, err := responseCache(cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: response cache disabled: %v\n", err)
+			return provider, nil
+		}
+		return llm.NewCachedProvider(provider, model, cache), nil
+	}
+	return provider, nil
+}
+
+// reportProvider tells the user on stderr which provider is in use.
+func reportProvider(provider llm.Provider) {
+	if provider == nil {
+		fmt.Fprintln(os.Stderr, "No LLM provider in use, using template output")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Using LLM provider: %s\n", provider.Name())
+}
+
+// useCacheFromFlags reports whether cmd may use the response cache.
+// Commands without the --no-cache flag never do.
+func useCacheFromFlags(cmd *cobra.Command, cfg *config.Config) bool {
+	noCache, err := cmd.Flags().GetBool("no-cache")
+	return err == nil && !noCache && cfg.Cache.Enabled
+}
+
+// contextWindowFor returns the configured context window, or the window of
+// model as reported by the provider or, when it reports none, as known for
+// the model name. An empty model is the configured model when the
+// configured provider is in use, otherwise the provider's default.
+func contextWindowFor(ctx context.Context, cfg *config.Config, provider llm.Provider, model string) int {
+	if cfg.LLM.ContextWindow > 0 {
+		return cfg.LLM.ContextWindow
+	}
+	if provider == nil {
+		return llm.ContextWindow(model)
+	}
+	if model == "" && provider.Name() == cfg.LLM.Provider {
+		model = cfg.LLM.Model
+	}
+	if model == "" {
+		model = llm.DefaultModel(provider)
+	}
+
+	if model != "" {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if models, err := llm.DiscoverModels(ctx, provider, modelCache(cfg)); err == nil {
+			if m, ok := llm.FindModel(models, model); ok && m.ContextLength > 0 {
+				return m.ContextLength
+			}
+		}
+	}
+	return llm.ContextWindow(model)
+}
+
+// budget returns the configured spending limits.
+func budget(cfg *config.Config) llm.Budget {
+	return llm.Budget{PerRun: cfg.Budget.PerRunUSD, PerDay: cfg.Budget.PerDayUSD}
+}
+
+// usageLedger opens the daily usage ledger, or returns nil when its
+// location cannot be determined.
+func usageLedger() *llm.Ledger {
+	path, err := config.UsagePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: usage will not be recorded: %v\n", err)
+		return nil
+	}
+	return llm.NewLedger(path)
+}
+
+// addCacheFlag registers the --no-cache flag on cmd. Commands without it
+// never use the response cache.
+func addCacheFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-cache", false, "Always send requests to the LLM instead of reusing cached responses")
+}
+
+// responseCache opens the LLM response cache with the configured limits.
+func responseCache(cfg *config.Config) (*llm.Cache, error) {
+	dir, err := config.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(cfg.Cache.TTLHours) * time.Hour
+	maxBytes := int64(cfg.Cache.MaxSizeMB) * 1024 * 1024
+	return llm.NewCache(dir, ttl, maxBytes), nil
+}
+
+// ollamaURL returns the configured Ollama endpoint when Ollama is the
+// configured provider, otherwise the detector default.
+func ollamaURL(cfg *config.Config) string {
+	if cfg.LLM.Provider == string(llm.ProviderOllama) {
+		return cfg.LLM.BaseURL
+	}
+	return ""
+}
+
+// loadConfig loads the user config, falling back to defaults on error.
+func loadConfig() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to load config, using defaults: %v\n", err)
+		cfg = config.GetDefault()
+	}
+	if err := cfg.MergeProject("."); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: ignoring project config: %v\n", err)
+	}
+	return cfg
+}
+
+// dirFromFlags returns the directory given with the named flag, or dir
+// from the config when the flag is not set.
+func dirFromFlags(cmd *cobra.Command, flag, dir string) string {
+	if value, _ := cmd.Flags().GetString(flag); value != "" {
+		return value
+	}
+	return dir
+}
+
+// addStreamFlag registers the --stream flag on cmd.
+func addStreamFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("stream", false, "Stream LLM output to stderr as it is generated")
+}
+
+// streamHandlerFromFlags returns a handler that writes generated text to
+// stderr when --stream is set, or nil.
+func streamHandlerFromFlags(cmd *cobra.Command) modes.StreamHandler {
+	if stream, _ := cmd.Flags().GetBool("stream"); !stream {
+		return nil
+	}
+	return func(chunk string) {
+		fmt.Fprint(os.Stderr, chunk)
+	}
+}
+
+// addToolsFlag registers the --tools flag for modes that can read the
+// codebase through tools.
+func addToolsFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("tools", false, "Let the model read the files it needs through tools instead of sending the whole codebase")
+}
+
+func useToolsFromFlags(cmd *cobra.Command) bool {
+	tools, _ := cmd.Flags().GetBool("tools")
+	return tools
+}
+
+// reportGeneration tells the user on stderr which provider produced the
+// output, or why the template was used instead.
+func reportGeneration(gen modes.Generation) {
+	switch {
+	case errors.Is(gen.Cause(), modes.ErrNoLLM):
+		fmt.Fprintln(os.Stderr, "Output generated from template")
+	case errors.Is(gen.Cause(), context.DeadlineExceeded):
+		fmt.Fprintf(os.Stderr, "warning: LLM request timed out, output generated from template: %s\n", gen.Error)
+	case gen.Templated():
+		fmt.Fprintf(os.Stderr, "warning: LLM request failed, output generated from template: %s\n", gen.Error)
+	case gen.Model != "":
+		fmt.Fprintf(os.Stderr, "Generated by %s (%s)%s\n", gen.Provider, gen.Model, cachedSuffix(gen))
+	default:
+		fmt.Fprintf(os.Stderr, "Generated by %s%s\n", gen.Provider, cachedSuffix(gen))
+	}
+	if usage := gen.UsageSummary(); usage != "" {
+		fmt.Fprintf(os.Stderr, "LLM usage: %s\n", usage)
+	}
+}
+
+func cachedSuffix(gen modes.Generation) string {
+	if gen.Cached {
+		return " [cached]"
+	}
+	return ""
+}
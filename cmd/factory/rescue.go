@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/modes"
+)
+
+var rescueCmd = &cobra.Command{
+	Use:   "rescue",
+	Short: "Start RESCUE mode (reverse-engineer codebase)",
+	Long: `Reverse-engineer a specification from an existing codebase.
+
+Writes current_spec.md to the contracts directory and alignment_report.md to
+the reports directory, then prints a JSON summary to stdout. Progress goes to
+stderr.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("path")
+
+		ctx := context.Background()
+		cfg := loadConfig()
+		contractsDir := dirFromFlags(cmd, "contracts-dir", cfg.Paths.SpecsDir)
+		reportsDir := dirFromFlags(cmd, "reports-dir", cfg.Paths.ReportsDir)
+		provider, settings, err := modeProviderFromFlags(ctx, cmd, cfg, modes.ModeRescue)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		rescue := modes.NewRescueMode(provider, contractsDir, reportsDir)
+		rescue.SetStreamHandler(streamHandlerFromFlags(cmd))
+		rescue.SetSettings(settings)
+		rescue.SetUseTools(useToolsFromFlags(cmd))
+		rescue.SetCodebasePath(path)
+
+		fmt.Fprintf(os.Stderr, "Scanning %s...\n", path)
+		result, err := rescue.ScanCodebase(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Scanned %d files\n", result.FilesScanned)
+		reportGeneration(result.GeneratedBy)
+
+		specPath, reportPath, err := rescue.SaveResults()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Spec saved to %s\n", specPath)
+		fmt.Fprintf(os.Stderr, "Report saved to %s\n", reportPath)
+
+		if err := printJSON(map[string]interface{}{
+			"codebase_path": result.CodebasePath,
+			"files_scanned": result.FilesScanned,
+			"spec_path":     specPath,
+			"report_path":   reportPath,
+			"generated_by":  result.GeneratedBy,
+		}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rescueCmd.Flags().String("path", ".", "Path to codebase")
+	rescueCmd.Flags().String("contracts-dir", "", "Directory to write the inferred spec to (default: paths.specs_dir)")
+	rescueCmd.Flags().String("reports-dir", "", "Directory to write the alignment report to (default: paths.reports_dir)")
+	addProviderFlags(rescueCmd)
+	addCacheFlag(rescueCmd)
+	addStreamFlag(rescueCmd)
+	addToolsFlag(rescueCmd)
+}
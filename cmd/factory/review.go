@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/modes"
+)
+
+// exitBelowThreshold is returned when --fail-under is set and the review
+// scored below it or produced no score.
+const exitBelowThreshold = 2
+
+var reviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Start REVIEW mode (check code against specs)",
+	Long: `Check code against a specification.
+
+With --spec the review runs headless: the report is written to the reports
+directory and printed to stdout as Markdown or JSON. Progress goes to stderr.
+
+Exit codes: 0 on success, 1 on error, 2 when --fail-under is set and the
+compliance score is below it or the review produced no score.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		spec, _ := cmd.Flags().GetString("spec")
+		paths, _ := cmd.Flags().GetStringSlice("path")
+		format, _ := cmd.Flags().GetString("format")
+		failUnder, _ := cmd.Flags().GetInt("fail-under")
+		questions, _ := cmd.Flags().GetStringArray("ask")
+
+		if spec == "" {
+			fmt.Fprintln(os.Stderr, "Error: --spec is required")
+			os.Exit(1)
+		}
+		if format != "markdown" && format != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown format %q (want markdown or json)\n", format)
+			os.Exit(1)
+		}
+		gated := cmd.Flags().Changed("fail-under")
+		if gated && (failUnder < 0 || failUnder > 100) {
+			fmt.Fprintf(os.Stderr, "Error: --fail-under must be between 0 and 100, got %d\n", failUnder)
+			os.Exit(1)
+		}
+
+		ctx := context.Background()
+		cfg := loadConfig()
+		reportsDir := dirFromFlags(cmd, "reports-dir", cfg.Paths.ReportsDir)
+		provider, settings, err := modeProviderFromFlags(ctx, cmd, cfg, modes.ModeReview)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		review := modes.NewReviewMode(provider, reportsDir)
+		review.SetStreamHandler(streamHandlerFromFlags(cmd))
+		review.SetSettings(settings)
+		review.SetUseTools(useToolsFromFlags(cmd))
+		if retrieve, _ := cmd.Flags().GetBool("retrieve"); retrieve && provider != nil {
+			index, err := codeIndex(cmd, cfg, provider)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			review.SetCodeIndex(index)
+		}
+		review.SetSpecFile(spec)
+		review.SetCodePaths(paths)
+
+		fmt.Fprintf(os.Stderr, "Reviewing %v against %s...\n", paths, spec)
+		result, err := review.RunReview(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		reportGeneration(result.GeneratedBy)
+
+		reportPath, err := review.SaveReport()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Report saved to %s\n", reportPath)
+
+		var followUps []followUp
+		for _, q := range questions {
+			answer, gen, err := review.Ask(ctx, q)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: follow-up %q: %v\n", q, err)
+				os.Exit(1)
+			}
+			reportGeneration(gen)
+			followUps = append(followUps, followUp{q, answer})
+		}
+
+		if format == "json" {
+			out := struct {
+				*modes.ReviewResult
+				ReportPath string     `json:"report_path"`
+				Passed     bool       `json:"passed"`
+				FollowUps  []followUp `json:"follow_ups,omitempty"`
+			}{result, reportPath, reviewPassed(result, gated, failUnder), followUps}
+			if err := printJSON(out); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Print(result.FullReport)
+			for _, f := range followUps {
+				fmt.Printf("\n## %s\n\n%s\n", f.Question, f.Answer)
+			}
+		}
+
+		switch {
+		case !gated:
+		case result.GeneratedBy.Templated():
+			fmt.Fprintln(os.Stderr, "No compliance score: the review fell back to template output")
+			os.Exit(exitBelowThreshold)
+		case result.ComplianceScore == nil:
+			fmt.Fprintln(os.Stderr, "No compliance score: none could be read from the report")
+			os.Exit(exitBelowThreshold)
+		case *result.ComplianceScore < failUnder:
+			fmt.Fprintf(os.Stderr, "Compliance score %d is below threshold %d\n", *result.ComplianceScore, failUnder)
+			os.Exit(exitBelowThreshold)
+		}
+	},
+}
+
+// reviewPassed reports whether result passes the --fail-under gate. Without
+// the gate every review passes; with it, a review from template output or
+// without a score never does.
+func reviewPassed(result *modes.ReviewResult, gated bool, failUnder int) bool {
+	if !gated {
+		return true
+	}
+	return !result.GeneratedBy.Templated() && result.ComplianceScore != nil && *result.ComplianceScore >= failUnder
+}
+
+// followUp is a question asked about a review and its answer
+type followUp struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+func init() {
+	reviewCmd.Flags().String("spec", "", "Specification file to review against")
+	reviewCmd.Flags().StringSlice("path", []string{"."}, "Code paths to review (repeatable)")
+	reviewCmd.Flags().String("format", "markdown", "Output format: markdown or json")
+	reviewCmd.Flags().Int("fail-under", 0, "Exit with code 2 if the compliance score is below this value (0-100) or the review produced no score")
+	reviewCmd.Flags().String("reports-dir", "", "Directory to write the review report to (default: paths.reports_dir)")
+	reviewCmd.Flags().StringArray("ask", nil, "Follow-up question about the review, answered with the review in context (repeatable)")
+	addProviderFlags(reviewCmd)
+	addCacheFlag(reviewCmd)
+	addStreamFlag(reviewCmd)
+	addToolsFlag(reviewCmd)
+	reviewCmd.Flags().Bool("retrieve", false, "When the code does not fit the context window, review the chunks most relevant to each spec section instead of the code in parts")
+	addEmbedModelFlag(reviewCmd)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ssdajoker/Code-Factory/internal/store"
+)
+
+// secretsPasswordEnv holds the password for the encrypted file store used
+// when the OS keyring is unavailable.
+const secretsPasswordEnv = "FACTORY_SECRETS_PASSWORD"
+
+// openSecretStore opens the keyring, or the encrypted file store when the
+// keyring is unavailable and a password is configured.
+func openSecretStore() (*store.AutoStore, error) {
+	password := os.Getenv(secretsPasswordEnv)
+	if password == "" && !store.NewKeyringStore().IsAvailable() {
+		return nil, fmt.Errorf("%w: set %s to use the encrypted file store", store.ErrKeyringUnavailable, secretsPasswordEnv)
+	}
+	return store.NewAutoStore(password)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ssdajoker/Code-Factory/internal/config"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
+)
+
+var templatesCmd = &cobra.Command{
+	Use:   "templates",
+	Short: "List, show and customise the LLM prompt templates",
+	Long: `Every prompt the modes send is a text/template. The built-in templates
+are overridden by files named <name>.tmpl in paths.template_dir and then in
+the project's .factory/templates, so teams can tune prompts without
+forking. Eject a template to start from the built-in text.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		templatesListCmd.Run(cmd, args)
+	},
+}
+
+var templatesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the templates and where each is loaded from",
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := promptSet(loadConfig()).List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, t := range list {
+			origin := "built-in"
+			if !t.BuiltIn() {
+				origin = t.Origin
+			}
+			fmt.Printf("%-28s %s\n", t.Name, origin)
+		}
+	},
+}
+
+var templatesShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print the template in use",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		t, err := promptSet(loadConfig()).Lookup(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !t.BuiltIn() {
+			fmt.Fprintf(os.Stderr, "Overridden by %s\n", t.Origin)
+		}
+		fmt.Println(t.Text)
+	},
+}
+
+var templatesEjectCmd = &cobra.Command{
+	Use:   "eject [name...]",
+	Short: "Copy built-in templates to the project for editing",
+	Long: `Copy the named built-in templates, or all of them, to the project's
+.factory/templates (or --dir). Files that already exist are kept unless
+--force is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, _ := cmd.Flags().GetString("dir")
+		force, _ := cmd.Flags().GetBool("force")
+
+		written, err := prompts.Eject(dir, args, force)
+		for _, path := range written {
+			fmt.Printf("✓ Wrote %s\n", path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(written) == 0 {
+			fmt.Fprintf(os.Stderr, "Nothing written: the templates already exist in %s (use --force to overwrite)\n", dir)
+		}
+	},
+}
+
+func init() {
+	templatesEjectCmd.Flags().String("dir", config.ProjectTemplatesDir("."), "Directory to write the templates to")
+	templatesEjectCmd.Flags().Bool("force", false, "Overwrite templates that already exist")
+
+	templatesCmd.AddCommand(templatesListCmd)
+	templatesCmd.AddCommand(templatesShowCmd)
+	templatesCmd.AddCommand(templatesEjectCmd)
+}
+
+// promptSet returns the prompt templates, overridden by those in the
+// configured template directory and then the project's .factory/templates.
+func promptSet(cfg *config.Config) *prompts.Set {
+	return prompts.NewSet(cfg.Paths.TemplateDir, config.ProjectTemplatesDir("."))
+}
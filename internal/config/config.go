@@ -20,20 +20,36 @@ type Config struct {
 
 	// Paths
 	Paths PathsConfig `toml:"paths"`
+
+	// LLM response cache
+	Cache CacheConfig `toml:"cache"`
+
+	// LLM spending limits
+	Budget BudgetConfig `toml:"budget"`
+
+	// Per-mode LLM routing
+	Modes ModesConfig `toml:"modes"`
 }
 
 // LLMConfig holds LLM provider settings
 type LLMConfig struct {
-	Provider    string `toml:"provider"`     // "openai", "anthropic", "ollama", "openrouter"
-	Model       string `toml:"model"`        // Model name
-	APIKeyStore string `toml:"api_key_store"` // "keyring", "env", "file"
-	BaseURL     string `toml:"base_url"`     // Custom API endpoint (for Ollama, etc.)
+	Provider               string            `toml:"provider"`                 // "openai", "anthropic", "ollama", "openrouter", "openai-compatible"
+	Model                  string            `toml:"model"`                    // Model name
+	APIKeyStore            string            `toml:"api_key_store"`            // "keyring", "env", "file"
+	BaseURL                string            `toml:"base_url"`                 // Custom API endpoint (for Ollama, etc.)
+	Headers                map[string]string `toml:"headers,omitempty"`        // Extra HTTP headers (for OpenAI-compatible servers)
+	MaxAttempts            int               `toml:"max_attempts"`             // Attempts per request before giving up on transient errors
+	Fallback               []string          `toml:"fallback,omitempty"`       // Providers tried in order by "auto", e.g. ["ollama", "anthropic"]
+	FallbackTimeoutSeconds int               `toml:"fallback_timeout_seconds"` // Time a provider in a fallback chain gets per request before the next is tried, 0 for no limit
+	ContextWindow          int               `toml:"context_window"`           // Model context window in tokens, 0 to infer from the model name; Ollama is asked for this much context
+	EmbedModel             string            `toml:"embed_model"`              // Embedding model for code retrieval, empty for the provider's default
 }
 
 // GitHubConfig holds GitHub integration settings
 type GitHubConfig struct {
 	TokenStorage string `toml:"token_storage"` // "keyring", "file", "env"
 	DefaultOrg   string `toml:"default_org"`   // Default organization
+	ClientID     string `toml:"client_id"`     // OAuth app client ID for device flow
 }
 
 // UIConfig holds UI preferences
@@ -50,6 +66,55 @@ type PathsConfig struct {
 	TemplateDir string `toml:"template_dir"` // Custom templates
 }
 
+// CacheConfig holds LLM response cache settings
+type CacheConfig struct {
+	Enabled   bool `toml:"enabled"`     // Serve repeated LLM requests from disk
+	TTLHours  int  `toml:"ttl_hours"`   // Hours before a cached response expires, 0 for never
+	MaxSizeMB int  `toml:"max_size_mb"` // Size limit before old entries are evicted, 0 for none
+
+	ModelsTTLHours int `toml:"models_ttl_hours"` // Hours before providers' model lists are fetched again, 0 for never
+}
+
+// BudgetConfig holds LLM spending limits in US dollars
+type BudgetConfig struct {
+	PerRunUSD float64 `toml:"per_run_usd"` // Abort a command before it spends more, 0 for no limit
+	PerDayUSD float64 `toml:"per_day_usd"` // Abort before the day's spending exceeds this, 0 for no limit
+}
+
+// ModesConfig holds the LLM settings of each mode, so that modes can use
+// different providers and models
+type ModesConfig struct {
+	Intake      ModeConfig `toml:"intake"`
+	Review      ModeConfig `toml:"review"`
+	Rescue      ModeConfig `toml:"rescue"`
+	ChangeOrder ModeConfig `toml:"change_order"`
+}
+
+// ModeConfig holds the LLM settings of a mode
+type ModeConfig struct {
+	Provider       string   `toml:"provider"`              // Provider for this mode, empty to auto-detect
+	Model          string   `toml:"model"`                 // Model for this mode, empty for the provider's default
+	Temperature    *float64 `toml:"temperature,omitempty"` // Sampling temperature, unset for the mode's default
+	MaxTokens      int      `toml:"max_tokens"`            // Most tokens per response
+	TimeoutSeconds int      `toml:"timeout_seconds"`       // Seconds before a run gives up on the LLM, 0 for no limit
+}
+
+// Mode returns the settings of the named mode: intake, review, rescue or
+// change_order
+func (m ModesConfig) Mode(name string) ModeConfig {
+	switch name {
+	case "intake":
+		return m.Intake
+	case "review":
+		return m.Review
+	case "rescue":
+		return m.Rescue
+	case "change_order":
+		return m.ChangeOrder
+	}
+	return ModeConfig{}
+}
+
 // configDir returns the Factory config directory
 func configDir() (string, error) {
 	home, err := os.UserHomeDir()
@@ -72,10 +137,12 @@ func configPath() (string, error) {
 func GetDefault() *Config {
 	return &Config{
 		LLM: LLMConfig{
-			Provider:    "ollama",
-			Model:       "llama3.2",
-			APIKeyStore: "keyring",
-			BaseURL:     "http://localhost:11434",
+			Provider:               "ollama",
+			Model:                  "llama3.2",
+			APIKeyStore:            "keyring",
+			BaseURL:                "http://localhost:11434",
+			MaxAttempts:            3,
+			FallbackTimeoutSeconds: 180,
 		},
 		GitHub: GitHubConfig{
 			TokenStorage: "keyring",
@@ -91,6 +158,19 @@ func GetDefault() *Config {
 			ReportsDir:  ".factory/reports",
 			TemplateDir: "",
 		},
+		Cache: CacheConfig{
+			Enabled:   true,
+			TTLHours:  168,
+			MaxSizeMB: 100,
+
+			ModelsTTLHours: 24,
+		},
+		Modes: ModesConfig{
+			Intake:      ModeConfig{MaxTokens: 4096},
+			Review:      ModeConfig{MaxTokens: 4096},
+			Rescue:      ModeConfig{MaxTokens: 8192},
+			ChangeOrder: ModeConfig{MaxTokens: 4096},
+		},
 	}
 }
 
@@ -147,3 +227,30 @@ func (c *Config) Save() error {
 func ConfigDir() (string, error) {
 	return configDir()
 }
+
+// UsagePath returns the path of the daily LLM usage ledger
+func UsagePath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "usage.json"), nil
+}
+
+// CacheDir returns the directory for cached LLM responses
+func CacheDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache", "llm"), nil
+}
+
+// ModelCacheDir returns the directory for cached provider model lists
+func ModelCacheDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache", "models"), nil
+}
@@ -3,6 +3,7 @@ package config
 import (
         "os"
         "path/filepath"
+        "strings"
         "testing"
 )
 
@@ -35,6 +36,9 @@ func TestGetDefault(t *testing.T) {
         if !cfg.UI.Animations {
                 t.Error("expected UI.Animations to be true by default")
         }
+        if cfg.LLM.FallbackTimeoutSeconds <= 0 {
+                t.Error("expected a per-attempt fallback timeout by default, so a hung provider fails over")
+        }
 }
 
 func TestLoadSave(t *testing.T) {
@@ -77,3 +81,76 @@ func TestConfigPath(t *testing.T) {
                 t.Errorf("expected config.toml, got %q", filepath.Base(path))
         }
 }
+
+func TestProjectConfigLoadSave(t *testing.T) {
+        root := t.TempDir()
+
+        if _, err := LoadProject(root); !os.IsNotExist(err) {
+                t.Fatalf("LoadProject() on empty dir error = %v, want not-exist", err)
+        }
+
+        cfg := NewProjectConfig("demo", GetDefault())
+        cfg.Project.Repository = "octocat/demo"
+        cfg.Project.Language = "Go"
+        if err := cfg.Save(root); err != nil {
+                t.Fatalf("Save() error = %v", err)
+        }
+
+        loaded, err := LoadProject(root)
+        if err != nil {
+                t.Fatalf("LoadProject() error = %v", err)
+        }
+        if loaded.Project != cfg.Project {
+                t.Errorf("Project = %+v, want %+v", loaded.Project, cfg.Project)
+        }
+        if loaded.Paths.SpecsDir != ".factory/specs" {
+                t.Errorf("Paths.SpecsDir = %q, want %q", loaded.Paths.SpecsDir, ".factory/specs")
+        }
+        if loaded.LLM.Provider != "ollama" {
+                t.Errorf("LLM.Provider = %q, want %q", loaded.LLM.Provider, "ollama")
+        }
+        data, err := os.ReadFile(ProjectConfigPath(root))
+        if err != nil {
+                t.Fatal(err)
+        }
+        if strings.Contains(string(data), "base_url") || strings.Contains(string(data), "headers") {
+                t.Errorf("project config should hold only project-scoped LLM settings:\n%s", data)
+        }
+}
+
+func TestMergeProject(t *testing.T) {
+        root := t.TempDir()
+        cfg := GetDefault()
+        cfg.LLM.Model = "llama3.2"
+        if err := cfg.MergeProject(root); err != nil {
+                t.Fatalf("MergeProject() without a project config error = %v", err)
+        }
+
+        if err := os.MkdirAll(filepath.Join(root, ProjectDirName), 0755); err != nil {
+                t.Fatal(err)
+        }
+        project := "[llm]\nprovider = \"anthropic\"\n\n[paths]\nreports_dir = \"out/reports\"\n"
+        if err := os.WriteFile(ProjectConfigPath(root), []byte(project), 0644); err != nil {
+                t.Fatal(err)
+        }
+        if err := cfg.MergeProject(root); err != nil {
+                t.Fatalf("MergeProject() error = %v", err)
+        }
+        if cfg.LLM.Provider != "anthropic" || cfg.Paths.ReportsDir != "out/reports" {
+                t.Errorf("project settings not applied: %+v, %+v", cfg.LLM, cfg.Paths)
+        }
+        if cfg.LLM.Model != "llama3.2" || cfg.Paths.SpecsDir != ".factory/specs" {
+                t.Errorf("settings missing from the project file should be kept: %+v, %+v", cfg.LLM, cfg.Paths)
+        }
+
+        project = "[llm]\nprovider = \"openai-compatible\"\nbase_url = \"https://example.com\"\n"
+        if err := os.WriteFile(ProjectConfigPath(root), []byte(project), 0644); err != nil {
+                t.Fatal(err)
+        }
+        if err := cfg.MergeProject(root); err == nil {
+                t.Error("MergeProject() should refuse a project config that sets llm.base_url")
+        }
+        if cfg.LLM.Provider != "anthropic" || cfg.LLM.BaseURL != GetDefault().LLM.BaseURL {
+                t.Errorf("a refused project config should leave the config unchanged: %+v", cfg.LLM)
+        }
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// File is an editable view of a config file that keeps track of which keys
+// are explicitly set, so unset keys keep falling back to defaults
+type File struct {
+	Path   string
+	values map[string]interface{}
+}
+
+// OpenFile reads the config file at path. A missing file is treated as empty.
+func OpenFile(path string) (*File, error) {
+	f := &File{Path: path, values: map[string]interface{}{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, err
+	}
+
+	if err := toml.Unmarshal(data, &f.values); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// OpenGlobal opens ~/.factory/config.toml
+func OpenGlobal() (*File, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenFile(path)
+}
+
+// IsSet reports whether key is explicitly set in the file
+func (f *File) IsSet(key string) bool {
+	_, ok := f.lookup(key)
+	return ok
+}
+
+// Set parses value according to the type of key and stores it
+func (f *File) Set(key, value string) error {
+	info, err := LookupKey(key)
+	if err != nil {
+		return err
+	}
+	v, err := info.ParseValue(value)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(key, ".")
+	table := f.values
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := table[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			table[part] = next
+		}
+		table = next
+	}
+	table[parts[len(parts)-1]] = v
+	return nil
+}
+
+// Unset removes key from the file. It returns false if key was not set.
+func (f *File) Unset(key string) (bool, error) {
+	if _, err := LookupKey(key); err != nil {
+		return false, err
+	}
+
+	parts := strings.Split(key, ".")
+	tables := []map[string]interface{}{f.values}
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := tables[len(tables)-1][part].(map[string]interface{})
+		if !ok {
+			return false, nil
+		}
+		tables = append(tables, next)
+	}
+
+	last := tables[len(tables)-1]
+	if _, ok := last[parts[len(parts)-1]]; !ok {
+		return false, nil
+	}
+	delete(last, parts[len(parts)-1])
+
+	// Drop tables left empty
+	for i := len(tables) - 1; i > 0; i-- {
+		if len(tables[i]) > 0 {
+			break
+		}
+		delete(tables[i-1], parts[i-1])
+	}
+	return true, nil
+}
+
+// Validate reports unknown keys, type mismatches and disallowed values
+func (f *File) Validate() []error {
+	var errs []error
+	walkValues(f.values, "", func(key string, v interface{}) {
+		info, err := LookupKey(key)
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if err := info.check(v); err != nil {
+			errs = append(errs, err)
+		}
+	})
+	return errs
+}
+
+// Config returns the defaults overlaid with the values in the file
+func (f *File) Config() (*Config, error) {
+	data, err := toml.Marshal(f.values)
+	if err != nil {
+		return nil, err
+	}
+	cfg := GetDefault()
+	if err := toml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Save writes the file, creating its directory if needed
+func (f *File) Save() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+	data, err := toml.Marshal(f.values)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, data, 0600)
+}
+
+func (f *File) lookup(key string) (interface{}, bool) {
+	var cur interface{} = f.values
+	for _, part := range strings.Split(key, ".") {
+		table, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = table[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func walkValues(m map[string]interface{}, prefix string, fn func(key string, v interface{})) {
+	for _, k := range sortedKeys(m) {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if table, ok := m[k].(map[string]interface{}); ok {
+			walkValues(table, key, fn)
+			continue
+		}
+		fn(key, m[k])
+	}
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownKey is returned for key paths that do not map onto Config
+var ErrUnknownKey = errors.New("unknown config key")
+
+// KeyInfo describes a configuration key
+type KeyInfo struct {
+	Key     string       // Dotted TOML path, e.g. "llm.model"
+	Kind    reflect.Kind // Value kind: string, bool, int, float64, slice or map
+	Allowed []string     // Permitted values (of each element for slices), empty when unrestricted
+}
+
+// allowedValues restricts string keys to a fixed set of values
+var allowedValues = map[string][]string{
+	"llm.provider":         {"ollama", "openai", "anthropic", "openrouter", "openai-compatible"},
+	"llm.fallback":         {"ollama", "openai", "anthropic", "openrouter", "openai-compatible"},
+	"llm.api_key_store":    {"keyring", "env", "file"},
+	"github.token_storage": {"keyring", "file", "env"},
+	"ui.theme":             {"dark", "light", "auto"},
+
+	"modes.intake.provider":       modeProviders,
+	"modes.review.provider":       modeProviders,
+	"modes.rescue.provider":       modeProviders,
+	"modes.change_order.provider": modeProviders,
+}
+
+// modeProviders are the providers a mode can be routed to
+var modeProviders = []string{"auto", "none", "ollama", "openai", "anthropic", "openrouter", "openai-compatible"}
+
+// Keys returns every configuration key in declaration order
+func Keys() []KeyInfo {
+	var keys []KeyInfo
+	walkKeys(reflect.TypeOf(Config{}), "", func(key string, field reflect.StructField) {
+		t := field.Type
+		if t.Kind() == reflect.Ptr {
+			// Optional values are set or unset, otherwise typed as usual
+			t = t.Elem()
+		}
+		keys = append(keys, KeyInfo{
+			Key:     key,
+			Kind:    t.Kind(),
+			Allowed: allowedValues[key],
+		})
+	})
+	return keys
+}
+
+// LookupKey returns the KeyInfo for key, or an ErrUnknownKey error that
+// suggests the closest known key. Entries of map keys such as
+// llm.headers.X-Api-Version are string keys of their own.
+func LookupKey(key string) (KeyInfo, error) {
+	keys := Keys()
+	for _, k := range keys {
+		if k.Key == key {
+			return k, nil
+		}
+		if k.Kind == reflect.Map && strings.HasPrefix(key, k.Key+".") && !strings.Contains(key[len(k.Key)+1:], ".") {
+			return KeyInfo{Key: key, Kind: reflect.String}, nil
+		}
+	}
+
+	best, bestDist := "", len(key)/2+2
+	for _, k := range keys {
+		if d := levenshtein(key, k.Key); d < bestDist {
+			best, bestDist = k.Key, d
+		}
+	}
+	if best != "" {
+		return KeyInfo{}, fmt.Errorf("%w %q (did you mean %q?)", ErrUnknownKey, key, best)
+	}
+	return KeyInfo{}, fmt.Errorf("%w %q", ErrUnknownKey, key)
+}
+
+// ParseValue converts s to the type of key and checks allowed values
+func (k KeyInfo) ParseValue(s string) (interface{}, error) {
+	var v interface{}
+	switch k.Kind {
+	case reflect.String:
+		v = s
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a boolean, got %q", k.Key, s)
+		}
+		v = b
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected an integer, got %q", k.Key, s)
+		}
+		v = n
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected a number, got %q", k.Key, s)
+		}
+		v = f
+	case reflect.Slice:
+		// Comma-separated list of strings
+		list := []interface{}{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v = list
+	case reflect.Map:
+		return nil, fmt.Errorf("%s is a table; set its entries as %s.<name>", k.Key, k.Key)
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %s", k.Key, k.Kind)
+	}
+	return v, k.check(v)
+}
+
+// check verifies a decoded TOML value has the key's type and an allowed value
+func (k KeyInfo) check(v interface{}) error {
+	switch k.Kind {
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %T", k.Key, v)
+		}
+		if len(k.Allowed) > 0 && s != "" && !containsString(k.Allowed, s) {
+			return fmt.Errorf("%s: invalid value %q (allowed: %s)", k.Key, s, strings.Join(k.Allowed, ", "))
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", k.Key, v)
+		}
+	case reflect.Int, reflect.Int64:
+		if _, ok := v.(int64); !ok {
+			return fmt.Errorf("%s: expected an integer, got %T", k.Key, v)
+		}
+	case reflect.Float64:
+		switch v.(type) {
+		case float64, int64:
+		default:
+			return fmt.Errorf("%s: expected a number, got %T", k.Key, v)
+		}
+	case reflect.Slice:
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected a list, got %T", k.Key, v)
+		}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("%s: expected a list of strings, got %T", k.Key, item)
+			}
+			if len(k.Allowed) > 0 && !containsString(k.Allowed, s) {
+				return fmt.Errorf("%s: invalid value %q (allowed: %s)", k.Key, s, strings.Join(k.Allowed, ", "))
+			}
+		}
+	case reflect.Map:
+		if _, ok := v.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s: expected a table, got %T", k.Key, v)
+		}
+	}
+	return nil
+}
+
+// Get returns the value of key in c
+func (c *Config) Get(key string) (interface{}, error) {
+	if _, err := LookupKey(key); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(c).Elem()
+	for _, part := range strings.Split(key, ".") {
+		if v.Kind() == reflect.Map {
+			if v = v.MapIndex(reflect.ValueOf(part)); !v.IsValid() {
+				return "", nil
+			}
+			continue
+		}
+		v = fieldByTag(v, part)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface(), nil
+}
+
+// FormatValue renders a config value for display
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+func walkKeys(t reflect.Type, prefix string, fn func(key string, field reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := tagName(field)
+		if name == "" {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if field.Type.Kind() == reflect.Struct {
+			walkKeys(field.Type, key, fn)
+			continue
+		}
+		fn(key, field)
+	}
+}
+
+func fieldByTag(v reflect.Value, name string) reflect.Value {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if tagName(t.Field(i)) == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+func tagName(field reflect.StructField) string {
+	tag := field.Tag.Get("toml")
+	if tag == "-" {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
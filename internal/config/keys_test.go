@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLookupKey(t *testing.T) {
+	if _, err := LookupKey("llm.model"); err != nil {
+		t.Errorf("LookupKey(llm.model) error = %v", err)
+	}
+
+	_, err := LookupKey("llm.provder")
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("LookupKey(llm.provder) error = %v, want ErrUnknownKey", err)
+	}
+	if !strings.Contains(err.Error(), `"llm.provider"`) {
+		t.Errorf("error %q should suggest llm.provider", err)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{"llm.model", "codellama:7b", "codellama:7b", false},
+		{"ui.animations", "false", false, false},
+		{"ui.animations", "maybe", nil, true},
+		{"llm.provider", "anthropic", "anthropic", false},
+		{"llm.provider", "gemini", nil, true},
+		{"modes.review.provider", "anthropic", "anthropic", false},
+		{"modes.review.provider", "gemini", nil, true},
+		{"modes.rescue.max_tokens", "8192", int64(8192), false},
+		{"modes.intake.temperature", "0.2", 0.2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			info, err := LookupKey(tt.key)
+			if err != nil {
+				t.Fatalf("LookupKey() error = %v", err)
+			}
+			got, err := info.ParseValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigGet(t *testing.T) {
+	cfg := GetDefault()
+	v, err := cfg.Get("paths.specs_dir")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if v != ".factory/specs" {
+		t.Errorf("Get(paths.specs_dir) = %v, want .factory/specs", v)
+	}
+
+	if v, err := cfg.Get("modes.review.temperature"); err != nil || v != "" {
+		t.Errorf("Get(modes.review.temperature) = %v, %v; want unset", v, err)
+	}
+	zero := 0.0
+	cfg.Modes.Review.Temperature = &zero
+	if v, err := cfg.Get("modes.review.temperature"); err != nil || v != 0.0 {
+		t.Errorf("Get(modes.review.temperature) = %v, %v; want 0", v, err)
+	}
+}
+
+func TestFileSetUnset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+
+	if err := f.Set("llm.model", "mistral"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := f.Set("llm.modle", "mistral"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Set(llm.modle) error = %v, want ErrUnknownKey", err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	f, err = OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if !f.IsSet("llm.model") || f.IsSet("llm.provider") {
+		t.Error("only llm.model should be set")
+	}
+	cfg, err := f.Config()
+	if err != nil {
+		t.Fatalf("Config() error = %v", err)
+	}
+	if cfg.LLM.Model != "mistral" || cfg.LLM.Provider != "ollama" {
+		t.Errorf("Config() llm = %+v, want model mistral over defaults", cfg.LLM)
+	}
+
+	removed, err := f.Unset("llm.model")
+	if err != nil || !removed {
+		t.Fatalf("Unset() = %v, %v", removed, err)
+	}
+	if f.IsSet("llm.model") {
+		t.Error("llm.model should no longer be set")
+	}
+}
+
+func TestFileValidate(t *testing.T) {
+	f := &File{values: map[string]interface{}{
+		"llm": map[string]interface{}{
+			"provder": "ollama",
+			"model":   "llama3.2",
+		},
+		"ui": map[string]interface{}{
+			"animations": "yes",
+		},
+	}}
+
+	errs := f.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("Validate() returned %d errors, want 2: %v", len(errs), errs)
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+
+	if err := f.Set("llm.headers", "x"); err == nil {
+		t.Error("Set(llm.headers) should require an entry name")
+	}
+	if err := f.Set("llm.headers.X-Api-Version", "2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if errs := f.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v", errs)
+	}
+
+	cfg, err := f.Config()
+	if err != nil {
+		t.Fatalf("Config() error = %v", err)
+	}
+	if cfg.LLM.Headers["X-Api-Version"] != "2" {
+		t.Errorf("Headers = %v", cfg.LLM.Headers)
+	}
+	if v, _ := cfg.Get("llm.headers.X-Api-Version"); v != "2" {
+		t.Errorf("Get() = %v, want 2", v)
+	}
+	if v, _ := cfg.Get("llm.headers.Missing"); v != "" {
+		t.Errorf("Get() of a missing entry = %v, want empty", v)
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	f, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+
+	if err := f.Set("llm.fallback", "ollama, anthropic,openai"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := f.Set("llm.fallback", "ollama,gemini"); err == nil {
+		t.Error("Set() should reject unknown providers in the list")
+	}
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	f, err = OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	if errs := f.Validate(); len(errs) != 0 {
+		t.Errorf("Validate() = %v", errs)
+	}
+	cfg, err := f.Config()
+	if err != nil {
+		t.Fatalf("Config() error = %v", err)
+	}
+	if got := FormatValue(cfg.LLM.Fallback); got != `["ollama", "anthropic", "openai"]` {
+		t.Errorf("Fallback = %s", got)
+	}
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// ProjectDirName is the per-project Factory directory
+const ProjectDirName = ".factory"
+
+// ProjectConfig holds project settings stored in {project}/.factory/config.toml
+type ProjectConfig struct {
+	// Project metadata
+	Project ProjectInfo `toml:"project"`
+
+	// LLM settings for this project
+	LLM ProjectLLMConfig `toml:"llm"`
+
+	// Paths, relative to the project root
+	Paths PathsConfig `toml:"paths"`
+}
+
+// ProjectInfo describes the project
+type ProjectInfo struct {
+	Name       string `toml:"name"`       // Project name
+	Repository string `toml:"repository"` // "owner/repo" when hosted on GitHub
+	Language   string `toml:"language"`   // Primary language
+}
+
+// ProjectLLMConfig holds the LLM settings a project may choose. Where
+// requests are sent and with which headers is left to the global config,
+// so that a checked-out repository cannot redirect them.
+type ProjectLLMConfig struct {
+	Provider string `toml:"provider"` // Provider for this project
+	Model    string `toml:"model"`    // Model for this project
+}
+
+// NewProjectConfig returns a project config seeded from the global config
+func NewProjectConfig(name string, global *Config) *ProjectConfig {
+	if global == nil {
+		global = GetDefault()
+	}
+	return &ProjectConfig{
+		Project: ProjectInfo{Name: name},
+		LLM:     ProjectLLMConfig{Provider: global.LLM.Provider, Model: global.LLM.Model},
+		Paths:   global.Paths,
+	}
+}
+
+// ProjectConfigPath returns the path to the project config under root
+func ProjectConfigPath(root string) string {
+	return filepath.Join(root, ProjectDirName, "config.toml")
+}
+
+// VectorStorePath returns the path to the code embeddings of the project
+// under root. They live in the gitignored cache as they can be rebuilt.
+func VectorStorePath(root string) string {
+	return filepath.Join(root, ProjectDirName, "cache", "vectors.json")
+}
+
+// ProjectTemplatesDir returns the directory of the prompt templates that
+// override the defaults for the project under root
+func ProjectTemplatesDir(root string) string {
+	return filepath.Join(root, ProjectDirName, "templates")
+}
+
+// LoadProject reads the project config under root
+func LoadProject(root string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(ProjectConfigPath(root))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := NewProjectConfig("", nil)
+	if err := toml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// MergeProject lays the llm.provider, llm.model and [paths] settings of
+// the project config under root over c. Settings the project file leaves
+// out keep their values. A project file that sets llm.base_url or
+// llm.headers is refused and c left unchanged. A missing project config is
+// not an error.
+func (c *Config) MergeProject(root string) error {
+	path := ProjectConfigPath(root)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var overlay struct {
+		LLM struct {
+			Provider string            `toml:"provider"`
+			Model    string            `toml:"model"`
+			BaseURL  string            `toml:"base_url"`
+			Headers  map[string]string `toml:"headers"`
+		} `toml:"llm"`
+		Paths PathsConfig `toml:"paths"`
+	}
+	overlay.LLM.Provider = c.LLM.Provider
+	overlay.LLM.Model = c.LLM.Model
+	overlay.Paths = c.Paths
+	if err := toml.Unmarshal(data, &overlay); err != nil {
+		return fmt.Errorf("invalid project config %s: %w", path, err)
+	}
+	if overlay.LLM.BaseURL != "" || len(overlay.LLM.Headers) > 0 {
+		return fmt.Errorf("project config %s sets llm.base_url or llm.headers, which only the global config may set", path)
+	}
+
+	c.LLM.Provider = overlay.LLM.Provider
+	c.LLM.Model = overlay.LLM.Model
+	c.Paths = overlay.Paths
+	return nil
+}
+
+// Save writes the project config under root
+func (p *ProjectConfig) Save(root string) error {
+	if err := os.MkdirAll(filepath.Join(root, ProjectDirName), 0755); err != nil {
+		return err
+	}
+
+	data, err := toml.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(ProjectConfigPath(root), data, 0644)
+}
+
+// Exists reports whether the global config file exists
+func Exists() bool {
+	path, err := configPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
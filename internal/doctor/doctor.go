@@ -0,0 +1,183 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ssdajoker/Code-Factory/internal/config"
+	"github.com/ssdajoker/Code-Factory/internal/github"
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/store"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a single diagnostic check
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"` // Remediation, set for warn and fail
+}
+
+func pass(name, msg string) Result {
+	return Result{Name: name, Status: StatusPass, Message: msg}
+}
+
+func warn(name, msg, hint string) Result {
+	return Result{Name: name, Status: StatusWarn, Message: msg, Hint: hint}
+}
+
+func fail(name, msg, hint string) Result {
+	return Result{Name: name, Status: StatusFail, Message: msg, Hint: hint}
+}
+
+// Failed reports whether any result failed
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckKeyring verifies the OS keyring can store secrets
+func CheckKeyring() Result {
+	if store.NewKeyringStore().IsAvailable() {
+		return pass("keyring", "OS keyring available")
+	}
+	return warn("keyring", "OS keyring unavailable, secrets fall back to encrypted files",
+		"Install and unlock a Secret Service provider (e.g. gnome-keyring), or set FACTORY_SECRETS_PASSWORD")
+}
+
+// CheckSecretsDir verifies the encrypted file store directory is private
+func CheckSecretsDir(dir string) Result {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return pass("secrets_dir", "Encrypted file store not in use")
+	}
+	if err != nil {
+		return fail("secrets_dir", fmt.Sprintf("Cannot stat %s: %v", dir, err), "Check ownership of "+dir)
+	}
+	if !info.IsDir() {
+		return fail("secrets_dir", dir+" is not a directory", "Remove "+dir+" and re-run factory github login")
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return warn("secrets_dir", fmt.Sprintf("%s is accessible by other users (%04o)", dir, perm), "Run: chmod 700 "+dir)
+	}
+	return pass("secrets_dir", dir+" is private")
+}
+
+// CheckConfig verifies the global config parses and only uses known keys
+func CheckConfig() Result {
+	f, err := config.OpenGlobal()
+	if err != nil {
+		return fail("config", err.Error(), "Fix the TOML syntax or run: factory config validate")
+	}
+	if _, err := os.Stat(f.Path); os.IsNotExist(err) {
+		return pass("config", "No config file, using defaults")
+	}
+	if errs := f.Validate(); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, e := range errs {
+			msgs[i] = e.Error()
+		}
+		return fail("config", strings.Join(msgs, "; "), "Run: factory config validate")
+	}
+	return pass("config", f.Path+" is valid")
+}
+
+// CheckOllama verifies Ollama is reachable and has models installed
+func CheckOllama(ctx context.Context, baseURL string) Result {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	ollama := llm.NewOllamaProvider(baseURL, "")
+	if !ollama.Available(ctx) {
+		return warn("ollama", "Ollama not reachable at "+baseURL,
+			"Install Ollama from https://ollama.ai and run: ollama serve")
+	}
+	models, err := ollama.Models(ctx)
+	if err != nil {
+		return warn("ollama", "Ollama reachable but listing models failed: "+err.Error(), "Check the Ollama server logs")
+	}
+	if len(models) == 0 {
+		return warn("ollama", "Ollama running but no models installed", "Run: ollama pull llama3.2")
+	}
+	return pass("ollama", fmt.Sprintf("Ollama running with %d models: %s", len(models), strings.Join(models, ", ")))
+}
+
+// CheckGitHub verifies the GitHub token is valid and has repo access.
+// tokenErr is the error from looking the token up, if any.
+func CheckGitHub(token string, tokenErr error) Result {
+	if errors.Is(tokenErr, store.ErrSecretNotFound) || (tokenErr == nil && token == "") {
+		return warn("github", "Not connected to GitHub", "Run: factory github login")
+	}
+	if errors.Is(tokenErr, store.ErrKeyringUnavailable) {
+		return warn("github", "Not connected to GitHub (no secret store available)",
+			"Set FACTORY_GITHUB_TOKEN, or set FACTORY_SECRETS_PASSWORD and run: factory github login")
+	}
+	if tokenErr != nil {
+		return fail("github", "Cannot read GitHub token: "+tokenErr.Error(), "Set FACTORY_GITHUB_TOKEN or fix the secret store")
+	}
+
+	perms, err := github.NewAppChecker(github.NewClient(token)).CheckPermissions()
+	if err != nil {
+		return fail("github", "GitHub token rejected: "+err.Error(), "Run: factory github logout && factory github login")
+	}
+	msg := fmt.Sprintf("Authenticated as %s (scopes: %s)", perms.Username, strings.Join(perms.Scopes, ", "))
+	if !perms.CanReadRepos {
+		return warn("github", msg, "Re-authenticate with the repo scope: factory github login")
+	}
+	return pass("github", msg)
+}
+
+// CheckWritable verifies dir can be written to, or created if missing
+func CheckWritable(name, dir string) Result {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		parent := filepath.Dir(dir)
+		for {
+			if _, err := os.Stat(parent); err == nil || parent == filepath.Dir(parent) {
+				break
+			}
+			parent = filepath.Dir(parent)
+		}
+		if err := probeWrite(parent); err != nil {
+			return fail(name, fmt.Sprintf("%s does not exist and %s is not writable", dir, parent), "Check permissions on "+parent)
+		}
+		return warn(name, dir+" does not exist yet", "It will be created on first use, or run: factory init")
+	}
+	if err != nil {
+		return fail(name, err.Error(), "Check permissions on "+dir)
+	}
+	if !info.IsDir() {
+		return fail(name, dir+" is not a directory", "Remove or rename "+dir)
+	}
+	if err := probeWrite(dir); err != nil {
+		return fail(name, dir+" is not writable: "+err.Error(), "Check permissions on "+dir)
+	}
+	return pass(name, dir+" is writable")
+}
+
+func probeWrite(dir string) error {
+	f, err := os.CreateTemp(dir, ".factory-doctor-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
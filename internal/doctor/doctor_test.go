@@ -0,0 +1,72 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ssdajoker/Code-Factory/internal/store"
+)
+
+func TestCheckSecretsDir(t *testing.T) {
+	root := t.TempDir()
+
+	if r := CheckSecretsDir(filepath.Join(root, "missing")); r.Status != StatusPass {
+		t.Errorf("missing dir: Status = %v, want pass", r.Status)
+	}
+
+	open := filepath.Join(root, "open")
+	os.Mkdir(open, 0755)
+	os.Chmod(open, 0755)
+	if r := CheckSecretsDir(open); r.Status != StatusWarn || r.Hint == "" {
+		t.Errorf("world-readable dir: got %+v, want warn with hint", r)
+	}
+
+	private := filepath.Join(root, "private")
+	os.Mkdir(private, 0700)
+	if r := CheckSecretsDir(private); r.Status != StatusPass {
+		t.Errorf("private dir: Status = %v, want pass", r.Status)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	root := t.TempDir()
+
+	if r := CheckWritable("reports_dir", root); r.Status != StatusPass {
+		t.Errorf("existing dir: got %+v, want pass", r)
+	}
+	if r := CheckWritable("reports_dir", filepath.Join(root, "a", "b")); r.Status != StatusWarn {
+		t.Errorf("missing dir: got %+v, want warn", r)
+	}
+
+	file := filepath.Join(root, "file")
+	os.WriteFile(file, nil, 0644)
+	if r := CheckWritable("reports_dir", file); r.Status != StatusFail {
+		t.Errorf("file: got %+v, want fail", r)
+	}
+}
+
+func TestCheckOllamaUnreachable(t *testing.T) {
+	r := CheckOllama(context.Background(), "http://invalid:99999")
+	if r.Status != StatusWarn || r.Hint == "" {
+		t.Errorf("got %+v, want warn with hint", r)
+	}
+}
+
+func TestCheckGitHubNotConnected(t *testing.T) {
+	r := CheckGitHub("", store.ErrSecretNotFound)
+	if r.Status != StatusWarn {
+		t.Errorf("Status = %v, want warn", r.Status)
+	}
+}
+
+func TestFailed(t *testing.T) {
+	results := []Result{pass("a", ""), warn("b", "", "")}
+	if Failed(results) {
+		t.Error("Failed() = true without failures")
+	}
+	if !Failed(append(results, fail("c", "", ""))) {
+		t.Error("Failed() = false with a failure")
+	}
+}
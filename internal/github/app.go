@@ -51,6 +51,7 @@ func (a *AppChecker) CheckPermissions() (*Permissions, error) {
 	return &Permissions{
 		Authenticated: true,
 		Username:      user.Login,
+		Scopes:        scopes,
 CanReadRepos:  hasScope(scopes, "repo") || hasScope(scopes, "public_repo"),
 		CanWriteRepos: hasScope(scopes, "repo") || hasScope(scopes, "public_repo"),
 	}, nil
@@ -136,6 +137,7 @@ func hasScope(scopes []string, target string) bool {
 type Permissions struct {
 	Authenticated bool
 	Username      string
+	Scopes        []string // OAuth scopes, empty when they could not be read
 	CanReadRepos  bool
 	CanWriteRepos bool
 }
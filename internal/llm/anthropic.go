@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -16,12 +17,13 @@ type AnthropicProvider struct {
 	model   string
 	baseURL string
 	client  *http.Client
+	retry   RetryPolicy
 }
 
 // NewAnthropicProvider creates a new Anthropic provider
 func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
 	if model == "" {
-		model = "claude-3-sonnet-20240229"
+		model = "claude-sonnet-4-20250514"
 	}
 	return &AnthropicProvider{
 		apiKey:  apiKey,
@@ -30,9 +32,15 @@ func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
 		client: &http.Client{
 			Timeout: 120 * time.Second,
 		},
+		retry: DefaultRetryPolicy(),
 	}
 }
 
+// SetRetryPolicy sets how transient failures are retried
+func (a *AnthropicProvider) SetRetryPolicy(policy RetryPolicy) {
+	a.retry = policy
+}
+
 func (a *AnthropicProvider) Name() string {
 	return "anthropic"
 }
@@ -42,68 +50,341 @@ func (a *AnthropicProvider) Available(ctx context.Context) bool {
 }
 
 func (a *AnthropicProvider) Models(ctx context.Context) ([]string, error) {
-	return []string{"claude-3-opus-20240229", "claude-3-sonnet-20240229", "claude-3-haiku-20240307"}, nil
-}
-
-func (a *AnthropicProvider) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
-	model := opts.Model
-	if model == "" {
-		model = a.model
+	models, err := a.ListModels(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	reqBody := map[string]interface{}{
-		"model":      model,
-		"max_tokens": opts.MaxTokens,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-	}
-	if opts.SystemPrompt != "" {
-		reqBody["system"] = opts.SystemPrompt
-	}
-	if opts.Temperature > 0 {
-		reqBody["temperature"] = opts.Temperature
-	}
-	if len(opts.Stop) > 0 {
-		reqBody["stop_sequences"] = opts.Stop
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
 	}
+	return names, nil
+}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
-	}
+// ListModels queries /models, following its pages. Context windows and
+// output limits are used when the API reports them, otherwise taken from
+// the known tables.
+func (a *AnthropicProvider) ListModels(ctx context.Context) ([]Model, error) {
+	var models []Model
+	after := ""
+	for {
+		url := a.baseURL + "/models?limit=1000"
+		if after != "" {
+			url += "&after_id=" + after
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("x-api-key", a.apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
 
-	req, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/messages", bytes.NewReader(jsonBody))
-	if err != nil {
-		return "", err
+		page, err := a.modelsPage(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page.Data {
+			model := describeModel(m.ID, CapabilityChat, CapabilityTools, CapabilityVision)
+			if m.MaxInputTokens > 0 {
+				model.ContextLength = m.MaxInputTokens
+			}
+			model.MaxOutput = m.MaxTokens
+			models = append(models, model)
+		}
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		after = page.LastID
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", a.apiKey)
-req.Header.Set("anthropic-version", "2023-06-01")
+}
+
+type anthropicModelsPage struct {
+	Data []struct {
+		ID             string `json:"id"`
+		MaxInputTokens int    `json:"max_input_tokens"`
+		MaxTokens      int    `json:"max_tokens"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
 
+func (a *AnthropicProvider) modelsPage(req *http.Request) (*anthropicModelsPage, error) {
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("anthropic request failed: %w", err)
+		return nil, fmt.Errorf("failed to list models: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("anthropic error %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError("anthropic", resp)
+	}
+	var page anthropicModelsPage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func (a *AnthropicProvider) endpoint() string {
+	return a.baseURL
+}
+
+func (a *AnthropicProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	resp, err := a.messages(ctx, a.client, req, false)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var result struct {
+		Model   string `json:"model"`
 		Content []struct {
-			Text string `json:"text"`
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			ID    string          `json:"id"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
 		} `json:"content"`
+		StopReason string         `json:"stop_reason"`
+		Usage      anthropicUsage `json:"usage"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if len(result.Content) == 0 {
-		return "", fmt.Errorf("no response from Anthropic")
+		return nil, fmt.Errorf("no response from Anthropic")
+	}
+
+	var text strings.Builder
+	var calls []ToolCall
+	for _, block := range result.Content {
+		switch block.Type {
+		case "", "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			calls = append(calls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+
+	return &GenerateResponse{
+		Text:         text.String(),
+		FinishReason: anthropicFinishReason(result.StopReason),
+		Model:        result.Model,
+		Usage:        result.Usage.usage(),
+		ToolCalls:    calls,
+	}, nil
+}
+
+// GenerateStream streams a completion from Anthropic's server-sent events
+func (a *AnthropicProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	resp, err := a.messages(ctx, streamClient, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan GenerateChunk)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		final := GenerateChunk{Done: true}
+		done := false
+		err := readSSE(resp.Body, func(event, data string) error {
+			switch event {
+			case "message_start":
+				var start struct {
+					Message struct {
+						Model string         `json:"model"`
+						Usage anthropicUsage `json:"usage"`
+					} `json:"message"`
+				}
+				if err := json.Unmarshal([]byte(data), &start); err != nil {
+					return fmt.Errorf("invalid anthropic stream event: %w", err)
+				}
+				final.Model = start.Message.Model
+				final.Usage = start.Message.Usage.usage()
+			case "content_block_delta":
+				var delta struct {
+					Delta struct {
+						Type string `json:"type"`
+						Text string `json:"text"`
+					} `json:"delta"`
+				}
+				if err := json.Unmarshal([]byte(data), &delta); err != nil {
+					return fmt.Errorf("invalid anthropic stream event: %w", err)
+				}
+				if delta.Delta.Type != "text_delta" || delta.Delta.Text == "" {
+					return nil
+				}
+				if !sendChunk(ctx, ch, GenerateChunk{Text: delta.Delta.Text}) {
+					return ctx.Err()
+				}
+			case "message_delta":
+				var delta struct {
+					Delta struct {
+						StopReason string `json:"stop_reason"`
+					} `json:"delta"`
+					Usage anthropicUsage `json:"usage"`
+				}
+				if err := json.Unmarshal([]byte(data), &delta); err != nil {
+					return fmt.Errorf("invalid anthropic stream event: %w", err)
+				}
+				final.FinishReason = anthropicFinishReason(delta.Delta.StopReason)
+				final.Usage.CompletionTokens = delta.Usage.OutputTokens
+			case "message_stop":
+				done = true
+				sendChunk(ctx, ch, final)
+				return io.EOF
+			case "error":
+				var e struct {
+					Error struct {
+						Type    string `json:"type"`
+						Message string `json:"message"`
+					} `json:"error"`
+				}
+				json.Unmarshal([]byte(data), &e)
+				return &APIError{
+					Provider: "anthropic",
+					Kind:     classifyError(0, e.Error.Type, e.Error.Message),
+					Message:  e.Error.Message,
+				}
+			}
+			// content_block_start/stop and ping carry no text
+			return nil
+		})
+		if err == io.EOF {
+			return
+		}
+		if err == nil && !done {
+			err = fmt.Errorf("anthropic stream ended unexpectedly")
+		}
+		if err != nil {
+			sendChunk(ctx, ch, GenerateChunk{Error: err})
+		}
+	}()
+	return ch, nil
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u anthropicUsage) usage() Usage {
+	return Usage{PromptTokens: u.InputTokens, CompletionTokens: u.OutputTokens}
+}
+
+// anthropicFinishReason maps Anthropic stop reasons onto the shared values
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return FinishStop
+	case "max_tokens":
+		return FinishLength
+	case "tool_use":
+		return FinishToolCalls
+	}
+	return reason
+}
+
+// anthropicMessage is a turn in Anthropic's format. Content is a string or
+// a list of content blocks.
+type anthropicMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// anthropicMessages splits the turns of req into the system prompt and
+// the messages Anthropic expects. Tool calls become tool_use blocks and tool
+// results become tool_result blocks of a single user turn.
+func anthropicMessages(req GenerateRequest) (string, []anthropicMessage) {
+	system := []string{}
+	if req.System != "" {
+		system = append(system, req.System)
+	}
+	messages := []anthropicMessage{}
+	for _, m := range requestMessages(req) {
+		switch {
+		case m.Role == RoleSystem:
+			system = append(system, m.Content)
+		case m.Role == RoleTool:
+			result := map[string]interface{}{"type": "tool_result", "tool_use_id": m.ToolCallID, "content": m.Content}
+			if n := len(messages); n > 0 && messages[n-1].Role == RoleUser {
+				if blocks, ok := messages[n-1].Content.([]interface{}); ok {
+					messages[n-1].Content = append(blocks, result)
+					continue
+				}
+			}
+			messages = append(messages, anthropicMessage{Role: RoleUser, Content: []interface{}{result}})
+		case len(m.ToolCalls) > 0:
+			blocks := []interface{}{}
+			if m.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+			}
+			for _, c := range m.ToolCalls {
+				input := c.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, map[string]interface{}{"type": "tool_use", "id": c.ID, "name": c.Name, "input": input})
+			}
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: blocks})
+		default:
+			messages = append(messages, anthropicMessage{Role: m.Role, Content: m.Content})
+		}
+	}
+	return strings.Join(system, "\n\n"), messages
+}
+
+// messages posts to /messages and returns the response on HTTP 200,
+// retrying transient failures
+func (a *AnthropicProvider) messages(ctx context.Context, client *http.Client, req GenerateRequest, stream bool) (*http.Response, error) {
+	model := req.Model
+	if model == "" {
+		model = a.model
+	}
+
+	// Anthropic takes the system prompt separately from the turns
+	system, messages := anthropicMessages(req)
+	reqBody := map[string]interface{}{
+		"model":      model,
+		"max_tokens": req.MaxTokens,
+		"messages":   messages,
+	}
+	if system != "" {
+		reqBody["system"] = system
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]interface{}, len(req.Tools))
+		for i, t := range req.Tools {
+			tools[i] = map[string]interface{}{"name": t.Name, "description": t.Description, "input_schema": t.Parameters}
+		}
+		reqBody["tools"] = tools
+	}
+	if req.Temperature > 0 {
+		reqBody["temperature"] = req.Temperature
+	}
+	if len(req.Stop) > 0 {
+		reqBody["stop_sequences"] = req.Stop
+	}
+	if stream {
+		reqBody["stream"] = true
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.baseURL+"/messages", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", a.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
 
-	return result.Content[0].Text, nil
+	return doRequest(client, httpReq, "anthropic", a.retry)
 }
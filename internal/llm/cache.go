@@ -0,0 +1,343 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cache stores generated responses on disk, one JSON file per request.
+// Entries older than the TTL are ignored, and the least recently used
+// entries are evicted once the cache grows beyond its size limit.
+type Cache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+}
+
+// cacheEntry is the on-disk format of a cached response
+type cacheEntry struct {
+	Created  time.Time        `json:"created"`
+	Response GenerateResponse `json:"response"`
+}
+
+// CacheStats summarises the contents of a cache directory
+type CacheStats struct {
+	Entries int
+	Bytes   int64
+}
+
+// NewCache creates a cache in dir. A zero ttl or maxBytes disables expiry
+// or the size limit respectively.
+func NewCache(dir string, ttl time.Duration, maxBytes int64) *Cache {
+	return &Cache{dir: dir, ttl: ttl, maxBytes: maxBytes, now: time.Now}
+}
+
+// Dir returns the cache directory
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the cached response for key, if present and not expired
+func (c *Cache) Get(key string) (*GenerateResponse, bool) {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		os.Remove(path)
+		return nil, false
+	}
+	if c.ttl > 0 && c.now().Sub(entry.Created) > c.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+
+	// Touch the entry so eviction removes the least recently used first
+	now := c.now()
+	os.Chtimes(path, now, now)
+	return &entry.Response, true
+}
+
+// Put stores resp under key and evicts old entries beyond the size limit
+func (c *Cache) Put(key string, resp *GenerateResponse) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+
+	now := c.now()
+	data, err := json.Marshal(cacheEntry{Created: now, Response: *resp})
+	if err != nil {
+		return err
+	}
+
+	// Write atomically so concurrent runs never read a partial entry
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	os.Chtimes(c.path(key), now, now)
+
+	return c.prune()
+}
+
+// Clear removes every entry and returns how many were removed
+func (c *Cache) Clear() (int, error) {
+	files, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, f := range files {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Stats returns the number and total size of cached entries
+func (c *Cache) Stats() (CacheStats, error) {
+	files, err := c.entries()
+	if err != nil {
+		return CacheStats{}, err
+	}
+	stats := CacheStats{Entries: len(files)}
+	for _, f := range files {
+		stats.Bytes += f.size
+	}
+	return stats, nil
+}
+
+type cacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries lists the cache files; a missing directory is an empty cache
+func (c *Cache) entries() ([]cacheFile, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []cacheFile
+	for _, e := range dirEntries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cacheFile{
+			path:    filepath.Join(c.dir, e.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// prune removes the least recently used entries until the cache fits
+func (c *Cache) prune() error {
+	if c.maxBytes <= 0 {
+		return nil
+	}
+	files, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= f.size
+	}
+	return nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// CachedProvider serves repeated requests from a Cache and stores new
+// responses. Cached responses are marked with Cached and cost nothing.
+type CachedProvider struct {
+	provider Provider
+	model    string
+	cache    *Cache
+}
+
+// NewCachedProvider wraps p with cache. model is the model p was configured
+// with, so changing the configured model does not return stale responses.
+func NewCachedProvider(p Provider, model string, cache *Cache) *CachedProvider {
+	return &CachedProvider{provider: p, model: model, cache: cache}
+}
+
+func (c *CachedProvider) Name() string {
+	return c.provider.Name()
+}
+
+func (c *CachedProvider) Available(ctx context.Context) bool {
+	return c.provider.Available(ctx)
+}
+
+func (c *CachedProvider) Models(ctx context.Context) ([]string, error) {
+	return c.provider.Models(ctx)
+}
+
+// ListModels describes the wrapped provider's models
+func (c *CachedProvider) ListModels(ctx context.Context) ([]Model, error) {
+	return listModels(ctx, c.provider)
+}
+
+func (c *CachedProvider) endpoint() string {
+	return endpointOf(c.provider)
+}
+
+func (c *CachedProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	key := c.key(req)
+	if resp, ok := c.cache.Get(key); ok {
+		resp.Cached = true
+		resp.Cost = 0
+		return resp, nil
+	}
+
+	resp, err := c.provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.store(key, resp)
+	return resp, nil
+}
+
+// Embed is not cached: embeddings are stored by the callers that need them
+func (c *CachedProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	return embedWith(ctx, c.provider, req)
+}
+
+// GenerateStream replays a cached response as a single chunk, or streams
+// from the wrapped provider and caches the response once it completes.
+func (c *CachedProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	key := c.key(req)
+	if resp, ok := c.cache.Get(key); ok {
+		ch := make(chan GenerateChunk, 2)
+		ch <- GenerateChunk{Text: resp.Text}
+		ch <- GenerateChunk{Done: true, FinishReason: resp.FinishReason, Model: resp.Model, Usage: resp.Usage, Provider: resp.Provider, Cached: true}
+		close(ch)
+		return ch, nil
+	}
+
+	chunks, err := streamRequest(ctx, c.provider, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan GenerateChunk)
+	go func() {
+		defer close(out)
+		var sb strings.Builder
+		for chunk := range chunks {
+			sb.WriteString(chunk.Text)
+			if chunk.Done && chunk.Error == nil {
+				c.store(key, &GenerateResponse{
+					Text:         sb.String(),
+					FinishReason: chunk.FinishReason,
+					Model:        chunk.Model,
+					Usage:        chunk.Usage,
+					Provider:     chunk.Provider,
+					Cost:         chunk.Cost,
+				})
+			}
+			if !sendChunk(ctx, out, chunk) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// store caches resp, ignoring empty responses and write failures; the
+// cache is an optimisation and must never fail a request
+func (c *CachedProvider) store(key string, resp *GenerateResponse) {
+	if strings.TrimSpace(resp.Text) == "" && len(resp.ToolCalls) == 0 {
+		return
+	}
+	c.cache.Put(key, resp)
+}
+
+// key hashes everything that affects the response
+func (c *CachedProvider) key(req GenerateRequest) string {
+	turns, _ := json.Marshal(requestMessages(req))
+	prompt := sha256.Sum256(turns)
+	data, _ := json.Marshal(struct {
+		Provider     string
+		Endpoint     string
+		Model        string
+		RequestModel string
+		System       string
+		Temperature  float64
+		MaxTokens    int
+		Window       int
+		Stop         []string
+		Schema       *JSONSchema
+		Tools        []ToolSpec
+		Prompt       string
+	}{
+		Provider:     c.provider.Name(),
+		Endpoint:     endpointOf(c.provider),
+		Model:        c.model,
+		RequestModel: req.Model,
+		System:       req.System,
+		Temperature:  req.Temperature,
+		MaxTokens:    req.MaxTokens,
+		Window:       req.ContextWindow,
+		Stop:         req.Stop,
+		Schema:       req.Schema,
+		Tools:        req.Tools,
+		Prompt:       hex.EncodeToString(prompt[:]),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
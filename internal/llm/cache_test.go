@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// countingProvider counts the requests that reach it
+type countingProvider struct {
+	MockProvider
+	calls int
+}
+
+func (c *countingProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	c.calls++
+	return c.MockProvider.Generate(ctx, req)
+}
+
+func TestCachedProvider(t *testing.T) {
+	inner := &countingProvider{MockProvider: MockProvider{name: "mock", response: "cached answer"}}
+	p := NewCachedProvider(inner, "m1", NewCache(t.TempDir(), time.Hour, 0))
+	ctx := context.Background()
+	req := GenerateRequest{Prompt: "review this", MaxTokens: 100}
+
+	first, err := p.Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if first.Cached {
+		t.Error("first response should not be cached")
+	}
+
+	second, err := p.Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !second.Cached || second.Text != "cached answer" {
+		t.Errorf("second response = %q cached=%t, want cached answer", second.Text, second.Cached)
+	}
+	if inner.calls != 1 {
+		t.Errorf("provider called %d times, want 1", inner.calls)
+	}
+
+	// Different options are a different request
+	req.MaxTokens = 200
+	if resp, _ := p.Generate(ctx, req); resp.Cached {
+		t.Error("changing MaxTokens should miss the cache")
+	}
+
+	// Stream replays the cached response
+	chunks, err := p.GenerateStream(ctx, req)
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	var text string
+	var done GenerateChunk
+	for c := range chunks {
+		text += c.Text
+		if c.Done {
+			done = c
+		}
+	}
+	if text != "cached answer" || !done.Cached {
+		t.Errorf("stream = %q cached=%t, want cached answer", text, done.Cached)
+	}
+	if inner.calls != 2 {
+		t.Errorf("provider called %d times, want 2", inner.calls)
+	}
+}
+
+func TestCachedProviderSeparatesEndpoints(t *testing.T) {
+	server := func(answer string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"model":"llama3","response":%q,"done":true}`, answer)
+		}))
+	}
+	first, second := server("from the first server"), server("from the second server")
+	defer first.Close()
+	defer second.Close()
+
+	cache := NewCache(t.TempDir(), time.Hour, 0)
+	ctx := context.Background()
+	req := GenerateRequest{Prompt: "review this", MaxTokens: 100}
+
+	if _, err := NewCachedProvider(NewOllamaProvider(first.URL, "llama3"), "llama3", cache).Generate(ctx, req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	resp, err := NewCachedProvider(NewOllamaProvider(second.URL, "llama3"), "llama3", cache).Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Cached || resp.Text != "from the second server" {
+		t.Errorf("second server got %q cached=%t, want its own answer", resp.Text, resp.Cached)
+	}
+
+	chain := func(url string) Provider {
+		return NewFallbackProvider(NewOllamaProvider(url, "llama3"))
+	}
+	if _, err := NewCachedProvider(chain(first.URL), "", cache).Generate(ctx, req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	resp, err = NewCachedProvider(chain(second.URL), "", cache).Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Cached || resp.Text != "from the second server" {
+		t.Errorf("chain of the second server got %q cached=%t, want its own answer", resp.Text, resp.Cached)
+	}
+
+	req.ContextWindow = 32768
+	resp, err = NewCachedProvider(NewOllamaProvider(first.URL, "llama3"), "llama3", cache).Generate(ctx, req)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Cached {
+		t.Error("a request for a different context window should not be served from the cache")
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	cache := NewCache(t.TempDir(), time.Hour, 0)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	if err := cache.Put("k", &GenerateResponse{Text: "x"}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, ok := cache.Get("k"); !ok {
+		t.Fatal("fresh entry should be returned")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := cache.Get("k"); ok {
+		t.Error("expired entry should not be returned")
+	}
+}
+
+func TestCacheSizeLimitAndClear(t *testing.T) {
+	cache := NewCache(t.TempDir(), 0, 500)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		now = now.Add(time.Minute)
+		if err := cache.Put(key, &GenerateResponse{Text: "0123456789012345678901234567890123456789"}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Bytes > 500 {
+		t.Errorf("cache holds %d bytes, want at most 500", stats.Bytes)
+	}
+	if _, ok := cache.Get("d"); !ok {
+		t.Error("newest entry should survive eviction")
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if removed != stats.Entries {
+		t.Errorf("Clear removed %d entries, want %d", removed, stats.Entries)
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("%d entries left after Clear", stats.Entries)
+	}
+}
@@ -0,0 +1,234 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNoRecording is returned by ReplayProvider for requests its cassette
+// does not hold
+var ErrNoRecording = errors.New("no recorded response")
+
+// Cassette holds request/response pairs recorded from a provider, for
+// replaying LLM conversations in tests without network access
+type Cassette struct {
+	Provider     string        `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and the response to it
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response GenerateResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request that identifies it in a
+// cassette. Sampling options and the model are left out so that tuning
+// them does not invalidate recordings.
+type RecordedRequest struct {
+	System   string    `json:"system,omitempty"`
+	Messages []Message `json:"messages"`
+	Schema   string    `json:"schema,omitempty"` // Name of the requested JSON schema
+	Tools    []string  `json:"tools,omitempty"`  // Names of the offered tools
+}
+
+func recordedRequest(req GenerateRequest) RecordedRequest {
+	r := RecordedRequest{System: req.System, Messages: requestMessages(req)}
+	if req.Schema != nil {
+		r.Schema = req.Schema.Name
+	}
+	for _, t := range req.Tools {
+		r.Tools = append(r.Tools, t.Name)
+	}
+	return r
+}
+
+func (r RecordedRequest) key() string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// LoadCassette reads a cassette from path
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path as indented JSON
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// RecordingProvider passes requests to a provider and records each
+// request with its response. Save writes the recording to a cassette.
+type RecordingProvider struct {
+	provider Provider
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecordingProvider records the requests sent to p
+func NewRecordingProvider(p Provider) *RecordingProvider {
+	return &RecordingProvider{provider: p, cassette: Cassette{Provider: p.Name()}}
+}
+
+// Save writes everything recorded so far to a cassette at path
+func (r *RecordingProvider) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(path)
+}
+
+func (r *RecordingProvider) Name() string {
+	return r.provider.Name()
+}
+
+func (r *RecordingProvider) Available(ctx context.Context) bool {
+	return r.provider.Available(ctx)
+}
+
+func (r *RecordingProvider) Models(ctx context.Context) ([]string, error) {
+	return r.provider.Models(ctx)
+}
+
+// ListModels describes the wrapped provider's models
+func (r *RecordingProvider) ListModels(ctx context.Context) ([]Model, error) {
+	return listModels(ctx, r.provider)
+}
+
+func (r *RecordingProvider) endpoint() string {
+	return endpointOf(r.provider)
+}
+
+func (r *RecordingProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	resp, err := r.provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r.record(req, *resp)
+	return resp, nil
+}
+
+func (r *RecordingProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	chunks, err := streamRequest(ctx, r.provider, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan GenerateChunk)
+	go func() {
+		defer close(out)
+		var text strings.Builder
+		for c := range chunks {
+			text.WriteString(c.Text)
+			if c.Done && c.Error == nil {
+				r.record(req, GenerateResponse{
+					Text:         text.String(),
+					FinishReason: c.FinishReason,
+					Model:        c.Model,
+					Usage:        c.Usage,
+					Provider:     c.Provider,
+				})
+			}
+			if !sendChunk(ctx, out, c) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *RecordingProvider) record(req GenerateRequest, resp GenerateResponse) {
+	resp.Cached, resp.Cost = false, 0
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{Request: recordedRequest(req), Response: resp})
+}
+
+// ReplayProvider answers requests from a cassette and fails those it does
+// not hold with ErrNoRecording. Identical requests are answered in the
+// order they were recorded, repeating the last answer once all are used.
+type ReplayProvider struct {
+	cassette *Cassette
+
+	mu   sync.Mutex
+	used []bool
+}
+
+// NewReplayProvider replays the cassette at path
+func NewReplayProvider(path string) (*ReplayProvider, error) {
+	c, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayProvider{cassette: c, used: make([]bool, len(c.Interactions))}, nil
+}
+
+// Name returns the name of the provider the cassette was recorded from
+func (r *ReplayProvider) Name() string {
+	if r.cassette.Provider == "" {
+		return "replay"
+	}
+	return r.cassette.Provider
+}
+
+func (r *ReplayProvider) Available(ctx context.Context) bool {
+	return true
+}
+
+func (r *ReplayProvider) Models(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (r *ReplayProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	key := recordedRequest(req).key()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i, in := range r.cassette.Interactions {
+		if in.Request.key() != key {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			resp := in.Response
+			return &resp, nil
+		}
+		last = i
+	}
+	if last >= 0 {
+		resp := r.cassette.Interactions[last].Response
+		return &resp, nil
+	}
+
+	prompt := ""
+	if messages := requestMessages(req); len(messages) > 0 {
+		prompt = messages[len(messages)-1].Content
+	}
+	if len(prompt) > 200 {
+		prompt = prompt[:200] + "..."
+	}
+	return nil, fmt.Errorf("%w for prompt %q", ErrNoRecording, prompt)
+}
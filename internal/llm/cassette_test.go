@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "review.json")
+	ctx := context.Background()
+
+	recorder := NewRecordingProvider(&scriptedProvider{
+		MockProvider: MockProvider{name: "ollama"},
+		answers:      []string{"first", "second", "streamed"},
+	})
+	opts := DefaultOptions()
+	for _, prompt := range []string{"Review it", "Review it"} {
+		if _, err := recorder.Generate(ctx, opts.Request(prompt)); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+	}
+	chunks, err := recorder.GenerateStream(ctx, opts.Request("Stream it"))
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if _, err := CollectStream(chunks, nil); err != nil {
+		t.Fatalf("stream failed: %v", err)
+	}
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	replay, err := NewReplayProvider(path)
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	if replay.Name() != "ollama" {
+		t.Errorf("Name() = %q, want the recorded provider", replay.Name())
+	}
+
+	// Sampling options do not affect matching
+	opts.Temperature = 0
+	for _, want := range []string{"first", "second", "second"} {
+		resp, err := replay.Generate(ctx, opts.Request("Review it"))
+		if err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+		if resp.Text != want {
+			t.Errorf("Text = %q, want %q", resp.Text, want)
+		}
+	}
+	text, err := Complete(ctx, replay, "Stream it", opts)
+	if err != nil || text != "streamed" {
+		t.Errorf("streamed recording replayed as %q, %v", text, err)
+	}
+
+	if _, err := replay.Generate(ctx, opts.Request("Something else")); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("unmatched prompt: error = %v, want ErrNoRecording", err)
+	}
+	system := opts.Request("Review it")
+	system.System = "A different system prompt"
+	if _, err := replay.Generate(ctx, system); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("different system prompt: error = %v, want ErrNoRecording", err)
+	}
+}
@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is one role-tagged turn of a conversation. Assistant turns may
+// call tools, whose results come back in RoleTool turns.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Tools called by an assistant turn
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Call answered by a tool turn
+	ToolName   string     `json:"tool_name,omitempty"`    // Tool that produced a tool turn
+}
+
+// Conversation is a multi-turn exchange with a provider. Every request
+// sends the whole history, so follow-up questions keep earlier context.
+type Conversation struct {
+	System   string
+	Messages []Message
+}
+
+// NewConversation starts a conversation with a system prompt
+func NewConversation(system string) *Conversation {
+	return &Conversation{System: system}
+}
+
+// AddUser appends a user message
+func (c *Conversation) AddUser(content string) {
+	c.Messages = append(c.Messages, Message{Role: RoleUser, Content: content})
+}
+
+// AddAssistant appends an assistant reply
+func (c *Conversation) AddAssistant(content string) {
+	c.Messages = append(c.Messages, Message{Role: RoleAssistant, Content: content})
+}
+
+// LastReply returns the most recent assistant reply, or "" if there is none
+func (c *Conversation) LastReply() string {
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		if c.Messages[i].Role == RoleAssistant {
+			return c.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// Request converts the conversation into a GenerateRequest. The
+// conversation's system prompt takes precedence over opts.SystemPrompt.
+func (c *Conversation) Request(opts Options) GenerateRequest {
+	req := opts.Request("")
+	if c.System != "" {
+		req.System = c.System
+	}
+	req.Messages = append([]Message(nil), c.Messages...)
+	return req
+}
+
+// Send appends text as a user message, sends the conversation to p and
+// appends the reply. On error the conversation is left unchanged.
+func (c *Conversation) Send(ctx context.Context, p Provider, text string, opts Options) (*GenerateResponse, error) {
+	c.AddUser(text)
+	resp, err := Chat(ctx, p, c, opts)
+	if err != nil {
+		c.Messages = c.Messages[:len(c.Messages)-1]
+		return nil, err
+	}
+	c.AddAssistant(resp.Text)
+	return resp, nil
+}
+
+// Chat sends conv to p and returns the reply without changing conv
+func Chat(ctx context.Context, p Provider, conv *Conversation, opts Options) (*GenerateResponse, error) {
+	return p.Generate(ctx, conv.Request(opts))
+}
+
+// requestMessages returns the turns of req, ending with Prompt as a user
+// message when it is set. System messages are left in place.
+func requestMessages(req GenerateRequest) []Message {
+	messages := append([]Message(nil), req.Messages...)
+	if req.Prompt != "" {
+		messages = append(messages, Message{Role: RoleUser, Content: req.Prompt})
+	}
+	return messages
+}
+
+// requestText returns all the text sent by req, for estimating its size
+func requestText(req GenerateRequest) string {
+	var sb strings.Builder
+	sb.WriteString(req.System)
+	for _, m := range requestMessages(req) {
+		sb.WriteString(m.Content)
+	}
+	return sb.String()
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConversationSend(t *testing.T) {
+	p := &scriptedProvider{answers: []string{"Score 60", "The handler skips logging"}}
+	conv := NewConversation("You review code.")
+
+	if _, err := conv.Send(context.Background(), p, "Review main.go", DefaultOptions()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	resp, err := conv.Send(context.Background(), p, "Explain the deviation", DefaultOptions())
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if resp.Text != "The handler skips logging" || conv.LastReply() != resp.Text {
+		t.Errorf("reply = %q, LastReply() = %q", resp.Text, conv.LastReply())
+	}
+
+	req := p.requests[1]
+	if req.System != "You review code." || req.Prompt != "" {
+		t.Errorf("request System = %q, Prompt = %q", req.System, req.Prompt)
+	}
+	want := []Message{
+		{Role: RoleUser, Content: "Review main.go"},
+		{Role: RoleAssistant, Content: "Score 60"},
+		{Role: RoleUser, Content: "Explain the deviation"},
+	}
+	if len(req.Messages) != len(want) {
+		t.Fatalf("sent %d messages, want %d", len(req.Messages), len(want))
+	}
+	for i, m := range want {
+		if req.Messages[i].Role != m.Role || req.Messages[i].Content != m.Content {
+			t.Errorf("message %d = %+v, want %+v", i, req.Messages[i], m)
+		}
+	}
+}
+
+func TestConversationSendErrorLeavesHistory(t *testing.T) {
+	p := &MockProvider{err: errors.New("offline")}
+	conv := NewConversation("")
+	conv.AddUser("Hello")
+	conv.AddAssistant("Hi")
+
+	if _, err := conv.Send(context.Background(), p, "Are you there?", DefaultOptions()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(conv.Messages) != 2 {
+		t.Errorf("conversation has %d messages after a failed send, want 2", len(conv.Messages))
+	}
+}
+
+func TestOllamaChat(t *testing.T) {
+	var path string
+	var body struct {
+		Messages []Message `json:"messages"`
+		Prompt   string    `json:"prompt"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"model":"llama3","message":{"role":"assistant","content":"Because of X"},"done":true,"prompt_eval_count":12,"eval_count":3}`))
+	}))
+	defer server.Close()
+
+	conv := NewConversation("You review code.")
+	conv.AddUser("Review it")
+	conv.AddAssistant("Score 60")
+	resp, err := conv.Send(context.Background(), NewOllamaProvider(server.URL, "llama3"), "Why?", DefaultOptions())
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if path != "/api/chat" {
+		t.Errorf("request went to %s, want /api/chat", path)
+	}
+	if len(body.Messages) != 4 || body.Messages[0].Role != RoleSystem || body.Messages[3].Content != "Why?" {
+		t.Errorf("messages = %+v, want the system prompt followed by every turn", body.Messages)
+	}
+	if resp.Text != "Because of X" || resp.Usage.TotalTokens() != 15 {
+		t.Errorf("response = %q with %d tokens", resp.Text, resp.Usage.TotalTokens())
+	}
+}
+
+func TestAnthropicMergesSystemMessages(t *testing.T) {
+	var body struct {
+		System   string    `json:"system"`
+		Messages []Message `json:"messages"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-3-haiku-20240307")
+	p.baseURL = server.URL
+
+	req := DefaultOptions().Request("")
+	req.System = "Be brief."
+	req.Messages = []Message{
+		{Role: RoleSystem, Content: "Answer in English."},
+		{Role: RoleUser, Content: "Hi"},
+	}
+	if _, err := p.Generate(context.Background(), req); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if body.System != "Be brief.\n\nAnswer in English." {
+		t.Errorf("system = %q", body.System)
+	}
+	if len(body.Messages) != 1 || body.Messages[0].Role != RoleUser {
+		t.Errorf("messages = %+v, want only the user turn", body.Messages)
+	}
+}
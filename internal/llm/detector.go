@@ -2,6 +2,7 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -19,6 +20,15 @@ type Detector struct {
 	ollamaURL     string
 	openAIKey     string
 	anthropicKey  string
+	openRouterKey string
+	maxAttempts   int
+
+	compatibleURL     string
+	compatibleKey     string
+	compatibleHeaders map[string]string
+
+	modelCache *ModelCache
+	hostedURLs map[ProviderType]string // Base URLs of hosted APIs, empty for the defaults
 }
 
 // NewDetector creates a new detector with the given credentials
@@ -33,38 +43,199 @@ func NewDetector(ollamaURL, openAIKey, anthropicKey string) *Detector {
 	}
 }
 
-// Detect checks all providers and returns the best available one
+// SetOpenRouterKey enables OpenRouter detection with the given API key
+func (d *Detector) SetOpenRouterKey(key string) {
+	d.openRouterKey = key
+}
+
+// SetMaxAttempts sets the retry attempts of providers built by the detector
+func (d *Detector) SetMaxAttempts(n int) {
+	d.maxAttempts = n
+}
+
+// SetOpenAICompatible enables detection of an OpenAI-compatible server at
+// baseURL. The API key and headers are optional.
+func (d *Detector) SetOpenAICompatible(baseURL, apiKey string, headers map[string]string) {
+	d.compatibleURL = baseURL
+	d.compatibleKey = apiKey
+	d.compatibleHeaders = headers
+}
+
+// SetModelCache keeps the model lists of hosted providers in cache, so
+// that detection queries them at most once per cache TTL
+func (d *Detector) SetModelCache(cache *ModelCache) {
+	d.modelCache = cache
+}
+
+// Detect checks the providers in priority order and returns the first
+// available one
 func (d *Detector) Detect(ctx context.Context) DetectionResult {
-	// Check Ollama first (local, free)
-	if result := d.checkOllama(ctx); result.Available {
-		return result
+	for _, check := range d.checks() {
+		if result := check(ctx); result.Available {
+			return result
+		}
+	}
+
+	return DetectionResult{
+		Available: false,
+		Message:   "No LLM provider available. Install Ollama or configure an API key.",
 	}
+}
+
+// DetectAll checks every provider in priority order (Ollama, an
+// OpenAI-compatible server when configured, OpenAI, Anthropic, OpenRouter)
+// and returns one result per provider, available or not
+func (d *Detector) DetectAll(ctx context.Context) []DetectionResult {
+	var results []DetectionResult
+	for _, check := range d.checks() {
+		results = append(results, check(ctx))
+	}
+	return results
+}
 
-	// Check OpenAI
-	if d.openAIKey != "" {
+// checks returns the provider checks in priority order
+func (d *Detector) checks() []func(context.Context) DetectionResult {
+	checks := []func(context.Context) DetectionResult{d.checkOllama}
+	if d.compatibleURL != "" {
+		checks = append(checks, d.checkOpenAICompatible)
+	}
+	return append(checks, d.checkOpenAI, d.checkAnthropic, d.checkOpenRouter)
+}
+
+func (d *Detector) checkOpenAI(ctx context.Context) DetectionResult {
+	return d.checkHosted(ctx, ProviderOpenAI, "OpenAI", d.openAIKey)
+}
+
+func (d *Detector) checkAnthropic(ctx context.Context) DetectionResult {
+	return d.checkHosted(ctx, ProviderAnthropic, "Anthropic", d.anthropicKey)
+}
+
+func (d *Detector) checkOpenRouter(ctx context.Context) DetectionResult {
+	return d.checkHosted(ctx, ProviderOpenRouter, "OpenRouter", d.openRouterKey)
+}
+
+// checkHosted lists the chat models of a hosted provider, the provider's
+// default model first. A rejected key makes the provider unavailable; when
+// the models cannot be listed for another reason it is assumed available
+// and its default model is used.
+func (d *Detector) checkHosted(ctx context.Context, providerType ProviderType, name, key string) DetectionResult {
+	if key == "" {
+		return DetectionResult{
+			ProviderType: providerType,
+			ProviderName: name,
+			Message:      "No " + name + " API key configured",
+		}
+	}
+	p, err := d.ProviderFor(DetectionResult{Available: true, ProviderType: providerType}, "")
+	if err != nil {
+		return DetectionResult{
+			ProviderType: providerType,
+			ProviderName: name,
+			Message:      err.Error(),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	models, err := DiscoverModels(ctx, p, d.modelCache)
+	if errors.Is(err, ErrAuth) {
+		return DetectionResult{
+			ProviderType: providerType,
+			ProviderName: name,
+			Message:      name + " API key rejected",
+		}
+	}
+	if err != nil {
 		return DetectionResult{
 			Available:    true,
-			ProviderType: ProviderOpenAI,
-			ProviderName: "OpenAI",
-			Models:       []string{"gpt-4", "gpt-4-turbo", "gpt-3.5-turbo"},
-			Message:      "OpenAI API key configured",
+			ProviderType: providerType,
+			ProviderName: name,
+			Message:      name + " API key configured, but listing models failed: " + err.Error(),
 		}
 	}
 
-	// Check Anthropic
-	if d.anthropicKey != "" {
+	return DetectionResult{
+		Available:    true,
+		ProviderType: providerType,
+		ProviderName: name,
+		Models:       chatModelNames(models, defaultModel(p)),
+		Message:      name + " API key configured",
+	}
+}
+
+// defaultModel returns the model p uses when a request names none
+func defaultModel(p Provider) string {
+	switch p := p.(type) {
+	case *OllamaProvider:
+		return p.model
+	case *OpenAIProvider:
+		return p.model
+	case *OpenRouterProvider:
+		return p.model
+	case *OpenAICompatibleProvider:
+		return p.model
+	case *AnthropicProvider:
+		return p.model
+	case *MeteredProvider:
+		return defaultModel(p.provider)
+	case *CachedProvider:
+		return defaultModel(p.provider)
+	case *RecordingProvider:
+		return defaultModel(p.provider)
+	}
+	return ""
+}
+
+// DefaultModel returns the model p generates with when none is requested,
+// or "" when it is not known
+func DefaultModel(p Provider) string {
+	return defaultModel(p)
+}
+
+// defaultEmbedModel returns the model p embeds with when none is requested
+func defaultEmbedModel(p Provider) string {
+	switch p := p.(type) {
+	case *OllamaProvider:
+		return ollamaEmbedModel
+	case *OpenAIProvider:
+		return p.embedModel
+	case *OpenRouterProvider:
+		return p.embedModel
+	case *OpenAICompatibleProvider:
+		return p.embedModel
+	}
+	return ""
+}
+
+func (d *Detector) checkOpenAICompatible(ctx context.Context) DetectionResult {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	server := NewOpenAICompatibleProvider(d.compatibleURL, d.compatibleKey, "", d.compatibleHeaders)
+	models, err := server.Models(ctx)
+	if err != nil {
+		return DetectionResult{
+			ProviderType: ProviderOpenAICompatible,
+			ProviderName: "OpenAI-compatible",
+			Message:      "OpenAI-compatible server not reachable at " + d.compatibleURL,
+		}
+	}
+	if len(models) == 0 {
 		return DetectionResult{
 			Available:    true,
-			ProviderType: ProviderAnthropic,
-			ProviderName: "Anthropic",
-			Models:       []string{"claude-3-opus", "claude-3-sonnet", "claude-3-haiku"},
-			Message:      "Anthropic API key configured",
+			ProviderType: ProviderOpenAICompatible,
+			ProviderName: "OpenAI-compatible",
+			Message:      "OpenAI-compatible server running but serving no models",
 		}
 	}
 
 	return DetectionResult{
-		Available: false,
-		Message:   "No LLM provider available. Install Ollama or configure an API key.",
+		Available:    true,
+		ProviderType: ProviderOpenAICompatible,
+		ProviderName: "OpenAI-compatible",
+		Models:       models,
+		Message:      "OpenAI-compatible server running with " + models[0],
 	}
 }
 
@@ -74,7 +245,12 @@ func (d *Detector) checkOllama(ctx context.Context) DetectionResult {
 
 	ollama := NewOllamaProvider(d.ollamaURL, "")
 	if !ollama.Available(ctx) {
-		return DetectionResult{Available: false}
+		return DetectionResult{
+			Available:    false,
+			ProviderType: ProviderOllama,
+			ProviderName: "Ollama",
+			Message:      "Ollama not reachable at " + d.ollamaURL,
+		}
 	}
 
 	models, err := ollama.Models(ctx)
@@ -107,19 +283,51 @@ func (d *Detector) GetBestProvider(ctx context.Context) (Provider, error) {
 	if len(result.Models) > 0 {
 		model = result.Models[0]
 	}
+	return d.ProviderFor(result, model)
+}
 
-	var apiKey string
+// ProviderFor builds a provider for a detection result using the given model
+func (d *Detector) ProviderFor(result DetectionResult, model string) (Provider, error) {
+	if !result.Available {
+		return nil, ErrNoProvider
+	}
+
+	var apiKey, baseURL string
+	var headers map[string]string
 	switch result.ProviderType {
+	case ProviderOllama:
+		baseURL = d.ollamaURL
+	case ProviderOpenAICompatible:
+		apiKey, baseURL, headers = d.compatibleKey, d.compatibleURL, d.compatibleHeaders
 	case ProviderOpenAI:
-		apiKey = d.openAIKey
+		apiKey, baseURL = d.openAIKey, d.hostedURLs[ProviderOpenAI]
 	case ProviderAnthropic:
-		apiKey = d.anthropicKey
+		apiKey, baseURL = d.anthropicKey, d.hostedURLs[ProviderAnthropic]
+	case ProviderOpenRouter:
+		apiKey, baseURL = d.openRouterKey, d.hostedURLs[ProviderOpenRouter]
 	}
 
 	return NewProvider(Config{
-		Type:    result.ProviderType,
-		APIKey:  apiKey,
-		BaseURL: d.ollamaURL,
-		Model:   model,
+		Type:        result.ProviderType,
+		APIKey:      apiKey,
+		BaseURL:     baseURL,
+		Model:       model,
+		Headers:     headers,
+		MaxAttempts: d.maxAttempts,
 	})
 }
+
+// MeasureLatency sends a minimal completion request and returns the round-trip time
+func MeasureLatency(ctx context.Context, provider Provider, model string) (time.Duration, error) {
+	opts := Options{
+		Temperature: 0,
+		MaxTokens:   8,
+		Model:       model,
+	}
+
+	start := time.Now()
+	if _, err := Complete(ctx, provider, "Reply with the single word OK.", opts); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
@@ -73,6 +73,26 @@ func TestDetectWithAPIKeys(t *testing.T) {
 	}
 }
 
+func TestDetectOpenRouter(t *testing.T) {
+	d := NewDetector("http://invalid:99999", "", "")
+	d.SetOpenRouterKey("sk-or-test")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result := d.Detect(ctx)
+	if !result.Available || result.ProviderType != ProviderOpenRouter {
+		t.Fatalf("Detect() = %+v, want available OpenRouter", result)
+	}
+
+	provider, err := d.ProviderFor(result, "")
+	if err != nil {
+		t.Fatalf("ProviderFor failed: %v", err)
+	}
+	if provider.Name() != "openrouter" {
+		t.Errorf("Name() = %v, want openrouter", provider.Name())
+	}
+}
+
 func TestDetectNoProviders(t *testing.T) {
 	d := NewDetector("http://invalid:99999", "", "")
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
@@ -103,3 +123,58 @@ func TestDetectionResult(t *testing.T) {
 		t.Errorf("expected 2 models, got %d", len(result.Models))
 	}
 }
+
+func TestDetectAll(t *testing.T) {
+	d := NewDetector("http://invalid:99999", "", "sk-ant-test")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	results := d.DetectAll(ctx)
+	want := []struct {
+		provider  ProviderType
+		available bool
+	}{
+		{ProviderOllama, false},
+		{ProviderOpenAI, false},
+		{ProviderAnthropic, true},
+		{ProviderOpenRouter, false},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("DetectAll() returned %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if results[i].ProviderType != w.provider {
+			t.Errorf("results[%d].ProviderType = %v, want %v", i, results[i].ProviderType, w.provider)
+		}
+		if results[i].Available != w.available {
+			t.Errorf("results[%d].Available = %v, want %v", i, results[i].Available, w.available)
+		}
+		if results[i].Message == "" {
+			t.Errorf("results[%d].Message should not be empty", i)
+		}
+	}
+}
+
+func TestMeasureLatency(t *testing.T) {
+	mock := &MockProvider{name: "test", available: true, response: "OK"}
+	if _, err := MeasureLatency(context.Background(), mock, ""); err != nil {
+		t.Errorf("MeasureLatency() error = %v", err)
+	}
+
+	mock.err = ErrProviderFailed
+	if _, err := MeasureLatency(context.Background(), mock, ""); err == nil {
+		t.Error("expected error from failing provider")
+	}
+}
+
+func TestDefaultModelUnwraps(t *testing.T) {
+	ollama := NewOllamaProvider("http://localhost:11434", "qwen2.5-coder:32b")
+	wrapped := NewCachedProvider(NewMeteredProvider(ollama, "", Budget{}, nil), "", nil)
+	if got := DefaultModel(wrapped); got != "qwen2.5-coder:32b" {
+		t.Errorf("DefaultModel() = %q, want qwen2.5-coder:32b", got)
+	}
+	if got := DefaultModel(&MockProvider{name: "test"}); got != "" {
+		t.Errorf("DefaultModel() = %q for an unknown provider, want empty", got)
+	}
+}
@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maxEmbedBatch is the most texts sent in one embedding request
+const maxEmbedBatch = 64
+
+// ErrNoEmbeddings is returned for providers that cannot embed text
+var ErrNoEmbeddings = errors.New("provider does not support embeddings")
+
+// Embedder is implemented by providers that can embed text as vectors
+type Embedder interface {
+	Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error)
+}
+
+// EmbedRequest represents a request to embed texts
+type EmbedRequest struct {
+	Model      string   // Embedding model, empty for the provider's default
+	Input      []string // Texts to embed
+	Dimensions int      // Shortens the vectors where the model supports it, 0 for the model's size
+}
+
+// EmbedResponse holds one vector per input, in input order
+type EmbedResponse struct {
+	Embeddings [][]float32
+	Model      string
+	Dimensions int   // Length of each vector
+	Usage      Usage // Input tokens, reported as prompt tokens
+	Cost       float64
+}
+
+// EmbedTexts embeds texts with p, in batches small enough for any
+// provider. It returns ErrNoEmbeddings when p cannot embed text.
+func EmbedTexts(ctx context.Context, p Provider, model string, texts []string) (*EmbedResponse, error) {
+	out := &EmbedResponse{Model: model}
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		end := min(start+maxEmbedBatch, len(texts))
+		resp, err := embedWith(ctx, p, EmbedRequest{Model: model, Input: texts[start:end]})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Embeddings) != end-start {
+			return nil, fmt.Errorf("%s returned %d embeddings for %d texts", p.Name(), len(resp.Embeddings), end-start)
+		}
+		if out.Dimensions != 0 && resp.Dimensions != out.Dimensions {
+			return nil, fmt.Errorf("%s returned vectors of %d and %d dimensions", p.Name(), out.Dimensions, resp.Dimensions)
+		}
+		out.Embeddings = append(out.Embeddings, resp.Embeddings...)
+		out.Model = resp.Model
+		out.Dimensions = resp.Dimensions
+		out.Usage = out.Usage.Add(resp.Usage)
+		out.Cost += resp.Cost
+	}
+	return out, nil
+}
+
+// dimensions returns the common length of vectors, or an error when they
+// differ
+func dimensions(vectors [][]float32) (int, error) {
+	if len(vectors) == 0 {
+		return 0, nil
+	}
+	n := len(vectors[0])
+	for _, v := range vectors[1:] {
+		if len(v) != n {
+			return 0, fmt.Errorf("embeddings have %d and %d dimensions", n, len(v))
+		}
+	}
+	return n, nil
+}
+
+// embedWith passes req to p when it can embed text
+func embedWith(ctx context.Context, p Provider, req EmbedRequest) (*EmbedResponse, error) {
+	e, ok := p.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p.Name(), ErrNoEmbeddings)
+	}
+	return e.Embed(ctx, req)
+}
@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// countingEmbedder embeds each text as its length and counts requests
+type countingEmbedder struct {
+	MockProvider
+	batches []int
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	e.batches = append(e.batches, len(req.Input))
+	resp := &EmbedResponse{Model: "test-embed", Dimensions: 2, Usage: Usage{PromptTokens: len(req.Input)}}
+	for _, text := range req.Input {
+		resp.Embeddings = append(resp.Embeddings, []float32{float32(len(text)), 1})
+	}
+	return resp, nil
+}
+
+func TestEmbedTextsBatches(t *testing.T) {
+	texts := make([]string, 2*maxEmbedBatch+1)
+	for i := range texts {
+		texts[i] = string(make([]byte, i))
+	}
+	e := &countingEmbedder{}
+
+	resp, err := EmbedTexts(context.Background(), e, "", texts)
+	if err != nil {
+		t.Fatalf("EmbedTexts failed: %v", err)
+	}
+	if len(e.batches) != 3 || e.batches[2] != 1 {
+		t.Errorf("batches = %v, want two full batches and one of 1", e.batches)
+	}
+	if len(resp.Embeddings) != len(texts) || resp.Embeddings[100][0] != 100 {
+		t.Errorf("embeddings out of order or missing: %d", len(resp.Embeddings))
+	}
+	if resp.Dimensions != 2 || resp.Usage.PromptTokens != len(texts) {
+		t.Errorf("Dimensions = %d, Usage = %+v", resp.Dimensions, resp.Usage)
+	}
+
+	if _, err := EmbedTexts(context.Background(), &MockProvider{name: "mock"}, "", texts); !errors.Is(err, ErrNoEmbeddings) {
+		t.Errorf("provider without embeddings: error = %v, want ErrNoEmbeddings", err)
+	}
+	chain := NewFallbackProvider(&MockProvider{name: "mock"}, e)
+	if _, err := EmbedTexts(context.Background(), NewMeteredProvider(chain, "", Budget{}, nil), "", texts[:1]); err != nil {
+		t.Errorf("fallback should skip providers that cannot embed: %v", err)
+	}
+}
+
+func TestProvidersEmbed(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		response string
+		provider func(url string) Provider
+		model    string
+	}{
+		{
+			name:     "ollama",
+			path:     "/api/embed",
+			response: `{"model":"nomic-embed-text","embeddings":[[1,0,0],[0,1,0]],"prompt_eval_count":6}`,
+			provider: func(url string) Provider { return NewOllamaProvider(url, "llama3.1") },
+			model:    "nomic-embed-text",
+		},
+		{
+			name:     "openai",
+			path:     "/embeddings",
+			response: `{"model":"text-embedding-3-small","data":[{"index":1,"embedding":[0,1,0]},{"index":0,"embedding":[1,0,0]}],"usage":{"prompt_tokens":6,"total_tokens":6}}`,
+			provider: func(url string) Provider {
+				p := NewOpenAIProvider("key", "gpt-4o")
+				p.baseURL = url
+				return p
+			},
+			model: "text-embedding-3-small",
+		},
+		{
+			name:     "openai-compatible",
+			path:     "/v1/embeddings",
+			response: `{"model":"bge-small","data":[{"index":0,"embedding":[1,0,0]},{"index":1,"embedding":[0,1,0]}]}`,
+			provider: func(url string) Provider { return NewOpenAICompatibleProvider(url, "", "bge-small", nil) },
+			model:    "bge-small",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("path = %s, want %s", r.URL.Path, tt.path)
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			resp, err := EmbedTexts(context.Background(), tt.provider(server.URL), "", []string{"first", "second"})
+			if err != nil {
+				t.Fatalf("EmbedTexts failed: %v", err)
+			}
+			if body["model"] != tt.model || len(body["input"].([]interface{})) != 2 {
+				t.Errorf("request = %v, want both inputs for %s", body, tt.model)
+			}
+			if resp.Dimensions != 3 || resp.Embeddings[0][0] != 1 || resp.Embeddings[1][1] != 1 {
+				t.Errorf("Embeddings = %v, want input order", resp.Embeddings)
+			}
+		})
+	}
+}
+
+func TestVectorStoreSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".factory", "cache", "vectors.json")
+	store, err := OpenVectorStore(path)
+	if err != nil {
+		t.Fatalf("OpenVectorStore failed: %v", err)
+	}
+	if err := store.Replace("test/embed", []VectorEntry{
+		{ID: "log.go:1-10", Hash: "a", Vector: []float32{1, 0}},
+		{ID: "notes.go:1-10", Hash: "b", Vector: []float32{0, 1}},
+		{ID: "both.go:1-10", Hash: "c", Vector: []float32{1, 1}},
+	}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	store, err = OpenVectorStore(path)
+	if err != nil {
+		t.Fatalf("reopening failed: %v", err)
+	}
+	matches, err := store.Search([]float32{0.9, 0.1}, 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != "log.go:1-10" || matches[1].ID != "both.go:1-10" {
+		t.Errorf("matches = %+v, want log.go then both.go", matches)
+	}
+	if _, err := store.Search([]float32{1, 0, 0}, 1); err == nil {
+		t.Error("query of the wrong size should fail")
+	}
+	if store.Vectors("other/model") != nil || store.Vectors("test/embed")["b"] == nil {
+		t.Error("vectors should only be reused for the model that embedded them")
+	}
+	if err := store.Replace("test/embed", []VectorEntry{{Vector: []float32{1}}, {Vector: []float32{1, 2}}}); err == nil {
+		t.Error("Replace should refuse vectors of different sizes")
+	}
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error kinds reported by providers. Use errors.Is to test an *APIError
+// against them.
+var (
+	ErrRateLimited   = errors.New("rate limited")
+	ErrOverloaded    = errors.New("provider overloaded")
+	ErrAuth          = errors.New("authentication failed")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrContextLength = errors.New("context length exceeded")
+)
+
+// APIError is a failed provider request
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Kind       error // One of the Err* kinds, or ErrProviderFailed
+	Message    string
+	RetryAfter time.Duration // Server-requested delay, zero if none
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s: %v: %s", e.Provider, e.Kind, e.Message)
+	}
+	return fmt.Sprintf("%s error %d (%v): %s", e.Provider, e.StatusCode, e.Kind, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+// Temporary reports whether the request may succeed if retried
+func (e *APIError) Temporary() bool {
+	switch {
+	case errors.Is(e.Kind, ErrRateLimited), errors.Is(e.Kind, ErrOverloaded):
+		return true
+	}
+	return e.StatusCode >= 500
+}
+
+// newAPIError reads a non-200 response into an *APIError
+func newAPIError(provider string, resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	errType, message := parseErrorBody(body)
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Kind:       classifyError(resp.StatusCode, errType, message),
+		Message:    message,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseErrorBody extracts the error type and message from the JSON error
+// formats used by OpenAI ({"error":{"type","message"}}), Anthropic
+// ({"type":"error","error":{...}}) and Ollama ({"error":"..."}), falling
+// back to the raw body
+func parseErrorBody(body []byte) (errType, message string) {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && len(envelope.Error) > 0 {
+		var detail struct {
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(envelope.Error, &detail) == nil && detail.Message != "" {
+			if detail.Code != "" {
+				return detail.Code, detail.Message
+			}
+			return detail.Type, detail.Message
+		}
+		var s string
+		if json.Unmarshal(envelope.Error, &s) == nil {
+			return "", s
+		}
+	}
+	return "", strings.TrimSpace(string(body))
+}
+
+// classifyError maps a status code and error details onto an error kind
+func classifyError(status int, errType, message string) error {
+	switch errType {
+	case "rate_limit_error", "rate_limit_exceeded":
+		return ErrRateLimited
+	case "overloaded_error":
+		return ErrOverloaded
+	case "authentication_error", "permission_error", "invalid_api_key":
+		return ErrAuth
+	case "insufficient_quota":
+		// Sent with a 429, but waiting does not restore credit
+		return ErrQuotaExceeded
+	case "context_length_exceeded":
+		return ErrContextLength
+	}
+
+	switch status {
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusServiceUnavailable, 529:
+		return ErrOverloaded
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		if isContextLengthMessage(message) {
+			return ErrContextLength
+		}
+	}
+	return ErrProviderFailed
+}
+
+// isContextLengthMessage recognises the context-length errors of providers
+// that do not report a dedicated error code
+func isContextLengthMessage(message string) bool {
+	m := strings.ToLower(message)
+	for _, s := range []string{"context length", "context window", "maximum context", "prompt is too long", "too many tokens"} {
+		if strings.Contains(m, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FallbackProvider tries an ordered chain of providers, failing over to the
+// next one when a request errors or times out. Responses name the provider
+// that answered in their Provider field.
+type FallbackProvider struct {
+	providers []Provider
+	timeout   time.Duration
+}
+
+// NewFallbackProvider creates a chain that tries providers in order
+func NewFallbackProvider(providers ...Provider) *FallbackProvider {
+	return &FallbackProvider{providers: providers}
+}
+
+// SetTimeout bounds each attempt so a hung provider fails over instead of
+// consuming the caller's whole deadline. Zero means no per-attempt limit.
+func (f *FallbackProvider) SetTimeout(d time.Duration) {
+	f.timeout = d
+}
+
+// Providers returns the chain in order
+func (f *FallbackProvider) Providers() []Provider {
+	return f.providers
+}
+
+func (f *FallbackProvider) Name() string {
+	names := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		names[i] = p.Name()
+	}
+	return "fallback(" + strings.Join(names, ", ") + ")"
+}
+
+// endpoint joins the endpoints of the chain, so that chains of the same
+// providers at different addresses are told apart
+func (f *FallbackProvider) endpoint() string {
+	endpoints := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		endpoints[i] = endpointOf(p)
+	}
+	return strings.Join(endpoints, ",")
+}
+
+// Available reports whether any provider in the chain is available
+func (f *FallbackProvider) Available(ctx context.Context) bool {
+	for _, p := range f.providers {
+		if p.Available(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// Models returns the models of the first provider that lists any
+func (f *FallbackProvider) Models(ctx context.Context) ([]string, error) {
+	var errs []error
+	for _, p := range f.providers {
+		models, err := p.Models(ctx)
+		if err == nil && len(models) > 0 {
+			return models, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f *FallbackProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	var errs []error
+	for _, p := range f.providers {
+		attemptCtx, cancel := f.attemptContext(ctx)
+		resp, err := p.Generate(attemptCtx, req)
+		cancel()
+		if err == nil {
+			if resp.Provider == "" {
+				resp.Provider = p.Name()
+			}
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, f.failed(errs)
+}
+
+// GenerateStream streams from the first provider that starts a stream.
+// Failover happens only before the stream starts; an error mid-stream is
+// delivered to the caller.
+func (f *FallbackProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	var errs []error
+	for _, p := range f.providers {
+		// The per-attempt timeout bounds starting the stream, not reading it
+		attemptCtx, cancel := context.WithCancel(ctx)
+		var timer *time.Timer
+		if f.timeout > 0 {
+			timer = time.AfterFunc(f.timeout, cancel)
+		}
+		chunks, err := streamRequest(attemptCtx, p, req)
+		if timer != nil {
+			timer.Stop()
+		}
+		if err == nil {
+			return withProvider(ctx, chunks, p.Name(), cancel), nil
+		}
+		cancel()
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, f.failed(errs)
+}
+
+// Embed embeds with the first provider in the chain that succeeds,
+// skipping those that cannot embed text
+func (f *FallbackProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	var errs []error
+	for _, p := range f.providers {
+		if _, ok := p.(Embedder); !ok {
+			continue
+		}
+		attemptCtx, cancel := f.attemptContext(ctx)
+		resp, err := embedWith(attemptCtx, p, req)
+		cancel()
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%s: %w", f.Name(), ErrNoEmbeddings)
+	}
+	return nil, f.failed(errs)
+}
+
+func (f *FallbackProvider) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if f.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, f.timeout)
+}
+
+func (f *FallbackProvider) failed(errs []error) error {
+	if len(errs) == 0 {
+		return ErrNoProvider
+	}
+	return fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// withProvider stamps the final chunk of a stream with the answering
+// provider and calls done when the stream ends or ctx is cancelled
+func withProvider(ctx context.Context, in <-chan GenerateChunk, name string, done func()) <-chan GenerateChunk {
+	out := make(chan GenerateChunk)
+	go func() {
+		defer close(out)
+		defer done()
+		for c := range in {
+			if c.Done && c.Provider == "" {
+				c.Provider = name
+			}
+			if !sendChunk(ctx, out, c) {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowProvider blocks until its context is done
+type slowProvider struct{ MockProvider }
+
+func (s *slowProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestFallbackFailsOver(t *testing.T) {
+	chain := NewFallbackProvider(
+		&MockProvider{name: "ollama", err: errors.New("connection refused")},
+		&MockProvider{name: "anthropic", response: "from anthropic"},
+		&MockProvider{name: "openai", response: "from openai"},
+	)
+
+	resp, err := chain.Generate(context.Background(), GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Text != "from anthropic" || resp.Provider != "anthropic" {
+		t.Errorf("Generate() = %q from %q, want anthropic", resp.Text, resp.Provider)
+	}
+	if chain.Name() != "fallback(ollama, anthropic, openai)" {
+		t.Errorf("Name() = %q", chain.Name())
+	}
+}
+
+func TestFallbackTimeout(t *testing.T) {
+	chain := NewFallbackProvider(
+		&slowProvider{MockProvider{name: "hung"}},
+		&MockProvider{name: "openai", response: "ok"},
+	)
+	chain.SetTimeout(20 * time.Millisecond)
+
+	resp, err := chain.Generate(context.Background(), GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Provider != "openai" {
+		t.Errorf("Provider = %q, want openai after the first provider timed out", resp.Provider)
+	}
+}
+
+func TestFallbackAllFail(t *testing.T) {
+	chain := NewFallbackProvider(
+		&MockProvider{name: "a", err: ErrRateLimited},
+		&MockProvider{name: "b", err: ErrAuth},
+	)
+
+	_, err := chain.Generate(context.Background(), GenerateRequest{Prompt: "hi"})
+	if !errors.Is(err, ErrRateLimited) || !errors.Is(err, ErrAuth) {
+		t.Errorf("error should wrap every provider's error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "a: ") || !strings.Contains(err.Error(), "b: ") {
+		t.Errorf("error should name each provider, got %v", err)
+	}
+}
+
+func TestFallbackStream(t *testing.T) {
+	chain := NewFallbackProvider(
+		&MockProvider{name: "down", err: errors.New("unreachable")},
+		&MockProvider{name: "up", response: "streamed"},
+	)
+
+	chunks, err := Stream(context.Background(), chain, "hi", DefaultOptions())
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	var text strings.Builder
+	var final GenerateChunk
+	for c := range chunks {
+		text.WriteString(c.Text)
+		if c.Done {
+			final = c
+		}
+	}
+	if text.String() != "streamed" || final.Provider != "up" {
+		t.Errorf("Stream() = %q from %q, want streamed from up", text.String(), final.Provider)
+	}
+}
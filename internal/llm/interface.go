@@ -2,51 +2,157 @@ package llm
 
 import (
 	"context"
+	"sort"
 )
 
-// LLMService defines the interface for all LLM providers
-type LLMService interface {
-	// Generate text completion
+// Provider is the interface implemented by all LLM providers
+type Provider interface {
+	// Generate produces a completion for the request
 	Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error)
-	
-	// Stream text completion
-	GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error)
-	
-	// List available models
-	ListModels(ctx context.Context) ([]Model, error)
-	
-	// Get provider name
-	Provider() string
+	// Name returns the provider name
+	Name() string
+	// Available checks if the provider is available
+	Available(ctx context.Context) bool
+	// Models returns available models
+	Models(ctx context.Context) ([]string, error)
 }
 
 // GenerateRequest represents a request to generate text
 type GenerateRequest struct {
-	Prompt      string
+	Prompt      string    // Final user message, sent after Messages
+	Messages    []Message // Earlier turns of a conversation
 	System      string
+	Model       string
 	Temperature float64
 	MaxTokens   int
 	Stop        []string
 	Context     map[string]string
+	Schema      *JSONSchema // Constrains the response to JSON, nil for free text
+	Tools       []ToolSpec  // Tools the model may call instead of answering
+
+	// ContextWindow is the number of tokens the prompt and response were
+	// planned to fit in. Providers that size their context per request,
+	// such as Ollama, use it; 0 keeps the server's default.
+	ContextWindow int
 }
 
 // GenerateResponse represents the response from text generation
 type GenerateResponse struct {
 	Text         string
-	TokensUsed   int
 	FinishReason string
 	Model        string
+	Usage        Usage
+	Provider     string     // Provider that answered, set when it differs from the one called
+	Cached       bool       // Served from the response cache
+	Cost         float64    // Price in US dollars, set by MeteredProvider
+	ToolCalls    []ToolCall // Tools the model called, with FinishReason FinishToolCalls
+}
+
+// Usage reports the tokens consumed by a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// TotalTokens returns prompt plus completion tokens
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add returns the sum of u and o
+func (u Usage) Add(o Usage) Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens + o.PromptTokens,
+		CompletionTokens: u.CompletionTokens + o.CompletionTokens,
+	}
 }
 
-// GenerateChunk represents a chunk of streamed response
+// Finish reasons reported in GenerateResponse and the final GenerateChunk.
+// Provider-specific reasons without an equivalent are passed through.
+const (
+	FinishStop      = "stop"
+	FinishLength    = "length"
+	FinishToolCalls = "tool_calls"
+)
+
+// GenerateChunk represents a chunk of streamed response. The final chunk
+// has Done set and carries the finish reason, model and usage when the
+// provider reports them.
 type GenerateChunk struct {
-	Text  string
-	Done  bool
-	Error error
+	Text         string
+	Done         bool
+	Error        error
+	FinishReason string
+	Model        string
+	Usage        Usage
+	Provider     string
+	Cached       bool
+	Cost         float64
 }
 
 // Model represents an LLM model
 type Model struct {
-	Name   string
-	Size   int64
-	Format string
+	Name          string
+	Size          int64
+	Format        string
+	ContextLength int          // Context window in tokens, 0 when unknown
+	MaxOutput     int          // Most completion tokens per request, 0 when unknown
+	Capabilities  []Capability // Empty when unknown
+	Pricing       Pricing
+}
+
+// Capability is a kind of request a model can serve
+type Capability string
+
+const (
+	CapabilityChat   Capability = "chat"
+	CapabilityTools  Capability = "tools"
+	CapabilityVision Capability = "vision"
+	CapabilityEmbed  Capability = "embed"
+)
+
+// Can reports whether the model is known to have capability c
+func (m Model) Can(c Capability) bool {
+	for _, have := range m.Capabilities {
+		if have == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Pricing is the cost of a model in US dollars per token
+type Pricing struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Cost returns the price of usage in US dollars
+func (p Pricing) Cost(u Usage) float64 {
+	return float64(u.PromptTokens)*p.Prompt + float64(u.CompletionTokens)*p.Completion
+}
+
+// ModelLister is implemented by providers that can describe their models
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]Model, error)
+}
+
+// SortModelsByPrice orders models from cheapest to most expensive by their
+// combined prompt and completion price
+func SortModelsByPrice(models []Model) {
+	sort.SliceStable(models, func(i, j int) bool {
+		pi := models[i].Pricing.Prompt + models[i].Pricing.Completion
+		pj := models[j].Pricing.Prompt + models[j].Pricing.Completion
+		return pi < pj
+	})
+}
+
+// Complete generates a completion for prompt and returns only its text. It
+// adapts the request/response API for callers that just need a string.
+func Complete(ctx context.Context, p Provider, prompt string, opts Options) (string, error) {
+	resp, err := p.Generate(ctx, opts.Request(prompt))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
 }
@@ -0,0 +1,351 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned instead of sending a request that could
+// take spending over a budget
+var ErrBudgetExceeded = errors.New("LLM budget exceeded")
+
+// Budget limits spending in US dollars. A zero limit is disabled.
+type Budget struct {
+	PerRun float64
+	PerDay float64
+}
+
+// Spend is the usage and cost of a run or a day
+type Spend struct {
+	Usage
+	Cost     float64 `json:"cost_usd"`
+	Requests int     `json:"requests"`
+}
+
+func (s *Spend) add(u Usage, cost float64) {
+	s.Usage = s.Usage.Add(u)
+	s.Cost += cost
+	s.Requests++
+}
+
+// ledgerRetention is how long daily totals are kept
+const ledgerRetention = 90 * 24 * time.Hour
+
+// Ledger records daily spending in a JSON file so the per-day budget holds
+// across runs
+type Ledger struct {
+	path string
+	now  func() time.Time
+	mu   sync.Mutex
+}
+
+// NewLedger creates a ledger stored at path
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path, now: time.Now}
+}
+
+// Today returns today's spending
+func (l *Ledger) Today() (Spend, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	days, err := l.load()
+	if err != nil {
+		return Spend{}, err
+	}
+	return days[l.day(l.now())], nil
+}
+
+// Record adds usage costing cost to today's total
+func (l *Ledger) Record(u Usage, cost float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	days, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	now := l.now()
+	today := days[l.day(now)]
+	today.add(u, cost)
+	days[l.day(now)] = today
+	for day := range days {
+		if t, err := time.ParseInLocation("2006-01-02", day, now.Location()); err == nil && now.Sub(t) > ledgerRetention {
+			delete(days, day)
+		}
+	}
+
+	data, err := json.MarshalIndent(days, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+func (l *Ledger) load() (map[string]Spend, error) {
+	days := make(map[string]Spend)
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return days, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &days); err != nil {
+		return nil, fmt.Errorf("invalid usage ledger %s: %w", l.path, err)
+	}
+	return days, nil
+}
+
+func (l *Ledger) day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// MeteredProvider prices every response, records it in a Ledger and
+// refuses requests whose worst-case cost would exceed the budget. The
+// worst case assumes the response uses all of MaxTokens. Local Ollama
+// models are free; while a budget is set, requests to models without a
+// known price are refused, as their cost cannot be bounded.
+type MeteredProvider struct {
+	provider Provider
+	model    string
+	budget   Budget
+	ledger   *Ledger
+
+	mu  sync.Mutex
+	run Spend
+}
+
+// NewMeteredProvider wraps p. model is the model p was configured with and
+// prices requests that do not name one. ledger may be nil, in which case
+// the per-day budget is not enforced.
+func NewMeteredProvider(p Provider, model string, budget Budget, ledger *Ledger) *MeteredProvider {
+	return &MeteredProvider{provider: p, model: model, budget: budget, ledger: ledger}
+}
+
+// Spent returns the usage and cost of every request made so far
+func (m *MeteredProvider) Spent() Spend {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.run
+}
+
+func (m *MeteredProvider) Name() string {
+	return m.provider.Name()
+}
+
+func (m *MeteredProvider) Available(ctx context.Context) bool {
+	return m.provider.Available(ctx)
+}
+
+func (m *MeteredProvider) Models(ctx context.Context) ([]string, error) {
+	return m.provider.Models(ctx)
+}
+
+// ListModels describes the wrapped provider's models
+func (m *MeteredProvider) ListModels(ctx context.Context) ([]Model, error) {
+	return listModels(ctx, m.provider)
+}
+
+func (m *MeteredProvider) endpoint() string {
+	return endpointOf(m.provider)
+}
+
+func (m *MeteredProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	if err := m.check(ctx, req); err != nil {
+		return nil, err
+	}
+	resp, err := m.provider.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Cost = m.record(ctx, req, resp.Model, resp.Usage)
+	return resp, nil
+}
+
+func (m *MeteredProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	if err := m.check(ctx, req); err != nil {
+		return nil, err
+	}
+	chunks, err := streamRequest(ctx, m.provider, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan GenerateChunk)
+	go func() {
+		defer close(out)
+		for c := range chunks {
+			if c.Done && c.Error == nil {
+				c.Cost = m.record(ctx, req, c.Model, c.Usage)
+			}
+			if !sendChunk(ctx, out, c) {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Embed embeds through the wrapped provider, pricing the usage like
+// generation. Requests that do not name a model are priced as the
+// provider's default embedding model.
+func (m *MeteredProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	tokens := 0
+	for _, text := range req.Input {
+		tokens += EstimateTokens(text)
+	}
+	model := req.Model
+	if model == "" {
+		model = defaultEmbedModel(m.provider)
+	}
+	price, known := pricing(ctx, m.provider, model, defaultEmbedModel)
+	if err := m.allow(price.Cost(Usage{PromptTokens: tokens}), known, model); err != nil {
+		return nil, err
+	}
+	resp, err := embedWith(ctx, m.provider, req)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := pricing(ctx, m.provider, resp.Model, defaultEmbedModel); ok {
+		price = p
+	}
+	resp.Cost = m.add(resp.Usage, price.Cost(resp.Usage))
+	return resp, nil
+}
+
+// price returns the pricing of the model that served req, trying the
+// response's model, the requested one and the configured one in turn, and
+// the provider's default model when none is named. The second result is
+// false when the price is unknown.
+func (m *MeteredProvider) price(ctx context.Context, req GenerateRequest, model string) (Pricing, string, bool) {
+	named := ""
+	for _, name := range []string{model, req.Model, m.model} {
+		if name == "" {
+			continue
+		}
+		if p, ok := pricing(ctx, m.provider, name, defaultModel); ok {
+			return p, name, true
+		}
+		if named == "" {
+			named = name
+		}
+	}
+	if named != "" {
+		return Pricing{}, named, false
+	}
+	p, ok := pricing(ctx, m.provider, "", defaultModel)
+	return p, defaultModel(m.provider), ok
+}
+
+// pricing returns the price of model on p, or of the model defaultOf
+// names for p when model is empty. OpenRouter models missing from the
+// price list are priced from the OpenRouter catalogue. A fallback chain is
+// priced at the most expensive of its providers.
+func pricing(ctx context.Context, p Provider, model string, defaultOf func(Provider) string) (Pricing, bool) {
+	switch p := p.(type) {
+	case *OllamaProvider:
+		return Pricing{}, true
+	case *FallbackProvider:
+		var worst Pricing
+		for _, q := range p.providers {
+			price, ok := pricing(ctx, q, model, defaultOf)
+			if !ok {
+				return Pricing{}, false
+			}
+			worst.Prompt = max(worst.Prompt, price.Prompt)
+			worst.Completion = max(worst.Completion, price.Completion)
+		}
+		return worst, true
+	}
+
+	if model == "" {
+		model = defaultOf(p)
+	}
+	if model == "" {
+		return Pricing{}, false
+	}
+	if price, ok := PriceFor(model); ok {
+		return price, true
+	}
+	if router, ok := p.(*OpenRouterProvider); ok {
+		if models, err := router.ListModels(ctx); err == nil {
+			if m, ok := FindModel(models, model); ok {
+				return m.Pricing, true
+			}
+		}
+	}
+	return Pricing{}, false
+}
+
+// check refuses req when its worst-case cost would exceed the budget
+func (m *MeteredProvider) check(ctx context.Context, req GenerateRequest) error {
+	price, model, known := m.price(ctx, req, "")
+	return m.allow(price.Cost(Usage{
+		PromptTokens:     EstimateTokens(requestText(req)),
+		CompletionTokens: req.MaxTokens,
+	}), known, model)
+}
+
+// allow refuses a request that may cost up to worst when spending has
+// reached the budget or the request could take it over. While a budget is
+// set, a request to model whose price is not known is refused.
+func (m *MeteredProvider) allow(worst float64, known bool, model string) error {
+	if m.budget.PerRun <= 0 && (m.budget.PerDay <= 0 || m.ledger == nil) {
+		return nil
+	}
+	if !known {
+		if model == "" {
+			model = "the default model of " + m.provider.Name()
+		}
+		return fmt.Errorf("%w: the price of %s is unknown, so the budget cannot be enforced; select a priced model or remove the budget",
+			ErrBudgetExceeded, model)
+	}
+
+	spent := m.Spent()
+	if m.budget.PerRun > 0 && (spent.Cost >= m.budget.PerRun || spent.Cost+worst > m.budget.PerRun) {
+		return fmt.Errorf("%w: this run has spent $%.4f of its $%.2f budget and the next request may cost up to $%.4f",
+			ErrBudgetExceeded, spent.Cost, m.budget.PerRun, worst)
+	}
+	if m.budget.PerDay > 0 && m.ledger != nil {
+		today, err := m.ledger.Today()
+		if err != nil {
+			return err
+		}
+		if today.Cost >= m.budget.PerDay || today.Cost+worst > m.budget.PerDay {
+			return fmt.Errorf("%w: $%.4f has been spent today of the $%.2f daily budget and the next request may cost up to $%.4f",
+				ErrBudgetExceeded, today.Cost, m.budget.PerDay, worst)
+		}
+	}
+	return nil
+}
+
+// record adds the usage of a response to the run and the ledger and
+// returns its cost. Ledger failures do not fail the request.
+func (m *MeteredProvider) record(ctx context.Context, req GenerateRequest, model string, u Usage) float64 {
+	price, _, _ := m.price(ctx, req, model)
+	return m.add(u, price.Cost(u))
+}
+
+// add records usage costing cost and returns the cost
+func (m *MeteredProvider) add(u Usage, cost float64) float64 {
+	m.mu.Lock()
+	m.run.add(u, cost)
+	m.mu.Unlock()
+	if m.ledger != nil {
+		m.ledger.Record(u, cost)
+	}
+	return cost
+}
@@ -0,0 +1,144 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPriceFor(t *testing.T) {
+	tests := []struct {
+		model  string
+		prompt float64
+		known  bool
+	}{
+		{"gpt-4o", 2.50e-6, true},
+		{"gpt-4o-mini-2024-07-18", 0.15e-6, true},
+		{"claude-3-5-sonnet-20241022", 3e-6, true},
+		{"anthropic/claude-3.5-sonnet", 3e-6, true},
+		{"llama3.1:8b", 0, false},
+	}
+	for _, tt := range tests {
+		p, ok := PriceFor(tt.model)
+		if ok != tt.known || math.Abs(p.Prompt-tt.prompt) > 1e-12 {
+			t.Errorf("PriceFor(%q) = %v, %t; want prompt %g, %t", tt.model, p.Prompt, ok, tt.prompt, tt.known)
+		}
+	}
+}
+
+func TestMeteredProviderRecordsCost(t *testing.T) {
+	ledger := NewLedger(filepath.Join(t.TempDir(), "usage.json"))
+	inner := &MockProvider{name: "openai", response: "hello"}
+	p := NewMeteredProvider(inner, "gpt-4o", Budget{}, ledger)
+
+	resp, err := p.Generate(context.Background(), GenerateRequest{Prompt: "0123456789"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	// MockProvider reports len(prompt) prompt and len(response) completion tokens
+	want := 10*2.50e-6 + 5*10.00e-6
+	if math.Abs(resp.Cost-want) > 1e-12 {
+		t.Errorf("Cost = %g, want %g", resp.Cost, want)
+	}
+	if spent := p.Spent(); spent.Requests != 1 || spent.TotalTokens() != 15 {
+		t.Errorf("Spent() = %+v, want 1 request of 15 tokens", spent)
+	}
+
+	today, err := ledger.Today()
+	if err != nil {
+		t.Fatalf("Today failed: %v", err)
+	}
+	if today.Requests != 1 || math.Abs(today.Cost-want) > 1e-12 {
+		t.Errorf("ledger today = %+v, want one request costing %g", today, want)
+	}
+}
+
+func TestMeteredProviderEnforcesBudget(t *testing.T) {
+	inner := &MockProvider{name: "anthropic", response: "ok"}
+	req := GenerateRequest{Prompt: "review", MaxTokens: 1000}
+
+	// 1000 completion tokens at $15/M may cost $0.015
+	p := NewMeteredProvider(inner, "claude-3-5-sonnet", Budget{PerRun: 0.01}, nil)
+	if _, err := p.Generate(context.Background(), req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Generate() error = %v, want ErrBudgetExceeded", err)
+	}
+
+	// The daily budget counts spending recorded by earlier runs
+	ledger := NewLedger(filepath.Join(t.TempDir(), "usage.json"))
+	ledger.Record(Usage{PromptTokens: 1000}, 0.99)
+	p = NewMeteredProvider(inner, "claude-3-5-sonnet", Budget{PerDay: 1}, ledger)
+	if _, err := p.Generate(context.Background(), req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Generate() error = %v, want ErrBudgetExceeded", err)
+	}
+
+	// Free local models are not refused until the budget is spent
+	ctx := context.Background()
+	p = NewMeteredProvider(NewOllamaProvider("http://localhost:11434", "llama3"), "", Budget{PerRun: 0.01}, nil)
+	if err := p.check(ctx, req); err != nil {
+		t.Errorf("check() error = %v, want nil for a free model", err)
+	}
+	p.add(Usage{}, 0.02)
+	if err := p.check(ctx, req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("check() error = %v, want ErrBudgetExceeded once the budget is spent", err)
+	}
+
+	// Models without a known price cannot be bounded
+	p = NewMeteredProvider(&MockProvider{name: "custom", response: "ok"}, "house-model", Budget{PerRun: 1}, nil)
+	if _, err := p.Generate(ctx, req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Generate() error = %v, want ErrBudgetExceeded for an unknown price", err)
+	}
+	p = NewMeteredProvider(&MockProvider{name: "custom", response: "ok"}, "house-model", Budget{}, nil)
+	if _, err := p.Generate(ctx, req); err != nil {
+		t.Errorf("Generate() error = %v, want nil without a budget", err)
+	}
+
+	// Without a model name the provider's default model is priced
+	p = NewMeteredProvider(NewAnthropicProvider("sk-ant-test", ""), "", Budget{PerRun: 0.01}, nil)
+	if err := p.check(ctx, req); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("check() error = %v, want the default model priced over budget", err)
+	}
+}
+
+func TestMeteredProviderPricesOpenRouterCatalogue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"id":"acme/house-model","pricing":{"prompt":"0.00001","completion":"0.00002"}}]}`)
+	}))
+	defer server.Close()
+
+	router := NewOpenRouterProvider("sk-or-test", "acme/house-model")
+	router.baseURL = server.URL
+	p := NewMeteredProvider(router, "", Budget{PerRun: 0.01}, nil)
+
+	// 1000 completion tokens at $20/M may cost $0.02
+	err := p.check(context.Background(), GenerateRequest{Prompt: "review", MaxTokens: 1000})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("check() error = %v, want ErrBudgetExceeded from the catalogue price", err)
+	}
+	if err := p.check(context.Background(), GenerateRequest{Prompt: "review", MaxTokens: 100}); err != nil {
+		t.Errorf("check() error = %v, want nil within the budget", err)
+	}
+}
+
+func TestLedgerStartsFreshEachDay(t *testing.T) {
+	ledger := NewLedger(filepath.Join(t.TempDir(), "usage.json"))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	ledger.now = func() time.Time { return now }
+
+	if err := ledger.Record(Usage{PromptTokens: 10}, 0.5); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	now = now.Add(24 * time.Hour)
+	today, err := ledger.Today()
+	if err != nil {
+		t.Fatalf("Today failed: %v", err)
+	}
+	if today.Requests != 0 || today.Cost != 0 {
+		t.Errorf("Today() = %+v on the next day, want nothing spent", today)
+	}
+}
@@ -0,0 +1,286 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnknownModel is returned for model names a provider does not serve
+var ErrUnknownModel = errors.New("unknown model")
+
+// ModelCache keeps the model lists of providers on disk, one JSON file per
+// provider, so that detection and model validation do not query the
+// provider on every run. Lists older than the TTL are fetched again.
+type ModelCache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// modelCacheEntry is the on-disk format of a cached model list
+type modelCacheEntry struct {
+	Fetched  time.Time `json:"fetched"`
+	Endpoint string    `json:"endpoint,omitempty"`
+	Models   []Model   `json:"models"`
+}
+
+// NewModelCache creates a model cache in dir. A zero ttl never expires
+// lists.
+func NewModelCache(dir string, ttl time.Duration) *ModelCache {
+	return &ModelCache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// Forget removes the cached list of p, so the next lookup fetches it
+func (c *ModelCache) Forget(p Provider) error {
+	if c == nil {
+		return nil
+	}
+	err := os.Remove(c.path(p))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (c *ModelCache) get(p Provider) ([]Model, bool) {
+	if c == nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(p))
+	if err != nil {
+		return nil, false
+	}
+	var entry modelCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if entry.Endpoint != endpointOf(p) {
+		return nil, false
+	}
+	if c.ttl > 0 && c.now().Sub(entry.Fetched) > c.ttl {
+		return nil, false
+	}
+	return entry.Models, true
+}
+
+func (c *ModelCache) put(p Provider, models []Model) error {
+	if c == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(modelCacheEntry{Fetched: c.now(), Endpoint: endpointOf(p), Models: models})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(p), data, 0600)
+}
+
+// path returns the cache file of p, named after the provider
+func (c *ModelCache) path(p Provider) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(p.Name()))
+	return filepath.Join(c.dir, name+".json")
+}
+
+// endpointOf returns the base URL of providers that report one, so that
+// lists from different servers of the same kind are not confused
+func endpointOf(p Provider) string {
+	if e, ok := p.(interface{ endpoint() string }); ok {
+		return e.endpoint()
+	}
+	return ""
+}
+
+// DiscoverModels returns the models p serves, from cache when a list was
+// fetched within its TTL, otherwise from the provider. A nil cache always
+// queries the provider.
+func DiscoverModels(ctx context.Context, p Provider, cache *ModelCache) ([]Model, error) {
+	if models, ok := cache.get(p); ok {
+		return models, nil
+	}
+	models, err := listModels(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	// The cache only saves requests; a failure to write it is not an error
+	cache.put(p, models)
+	return models, nil
+}
+
+// listModels returns the models of p, with metadata when p can describe
+// them
+func listModels(ctx context.Context, p Provider) ([]Model, error) {
+	if lister, ok := p.(ModelLister); ok {
+		return lister.ListModels(ctx)
+	}
+	names, err := p.Models(ctx)
+	if err != nil {
+		return nil, err
+	}
+	models := make([]Model, len(names))
+	for i, name := range names {
+		models[i] = Model{Name: name}
+	}
+	return models, nil
+}
+
+// describeModel returns a model with the context window and price known
+// for its name, for providers whose listing reports only names
+func describeModel(name string, capabilities ...Capability) Model {
+	m := Model{Name: name, Capabilities: capabilities}
+	m.ContextLength, _ = knownContextWindow(name)
+	m.Pricing, _ = PriceFor(name)
+	return m
+}
+
+// CheckModel returns an ErrUnknownModel error suggesting the closest names
+// when models does not include model. Besides exact names it accepts
+// Ollama names without the :latest tag and aliases of dated snapshots,
+// such as gpt-4o for gpt-4o-2024-08-06 or claude-3-5-sonnet-latest.
+func CheckModel(models []Model, provider, model string) error {
+	if _, ok := FindModel(models, model); ok {
+		return nil
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	if suggestions := closestNames(model, names, 3); len(suggestions) > 0 {
+		return fmt.Errorf("%w %q for %s (did you mean %s?)", ErrUnknownModel, model, provider, orList(suggestions))
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("%w %q for %s: it serves no models", ErrUnknownModel, model, provider)
+	}
+	return fmt.Errorf("%w %q for %s; run factory llm models to list them", ErrUnknownModel, model, provider)
+}
+
+// FindModel returns the listed model the name model refers to, matching
+// names as CheckModel does
+func FindModel(models []Model, model string) (Model, bool) {
+	for _, m := range models {
+		if modelMatches(m.Name, model) {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// modelMatches reports whether the name model refers to the listed model
+func modelMatches(listed, model string) bool {
+	if listed == model || listed == model+":latest" {
+		return true
+	}
+	alias := strings.TrimSuffix(model, "-latest")
+	return strings.HasPrefix(listed, alias+"-") && isSnapshotSuffix(listed[len(alias)+1:])
+}
+
+// isSnapshotSuffix reports whether s is a snapshot date such as 20240620
+// or 2024-08-06
+func isSnapshotSuffix(s string) bool {
+	digits := strings.ReplaceAll(s, "-", "")
+	if len(digits) != 8 {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// closestNames returns up to n names within a small edit distance of
+// name, closest first
+func closestNames(name string, names []string, n int) []string {
+	type candidate struct {
+		name string
+		dist int
+	}
+	limit := len(name)/3 + 2
+	var candidates []candidate
+	for _, other := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(other)); d <= limit {
+			candidates = append(candidates, candidate{other, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var closest []string
+	for _, c := range candidates {
+		if len(closest) == n {
+			break
+		}
+		closest = append(closest, c.name)
+	}
+	return closest
+}
+
+// orList quotes names and joins them as "a", "b" or "c"
+func orList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = fmt.Sprintf("%q", name)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// chatModelNames returns the names of the chat models in models, sorted,
+// with preferred first when it is among them
+func chatModelNames(models []Model, preferred string) []string {
+	var names []string
+	for _, m := range models {
+		if m.Can(CapabilityChat) && m.Name != preferred {
+			names = append(names, m.Name)
+		}
+	}
+	sort.Strings(names)
+	for _, m := range models {
+		if m.Name == preferred && m.Can(CapabilityChat) {
+			return append([]string{preferred}, names...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,188 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenAIListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		fmt.Fprint(w, `{"object":"list","data":[
+			{"id":"gpt-4o-mini","object":"model"},
+			{"id":"text-embedding-3-small","object":"model"},
+			{"id":"whisper-1","object":"model"}
+		]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("sk-test", "")
+	p.baseURL = server.URL
+
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 3 {
+		t.Fatalf("Expected 3 models, got %d", len(models))
+	}
+	chat := models[0]
+	if chat.ContextLength != 128000 || chat.Pricing.Prompt == 0 || !chat.Can(CapabilityTools) || !chat.Can(CapabilityVision) {
+		t.Errorf("Unexpected metadata for %s: %+v", chat.Name, chat)
+	}
+	if !models[1].Can(CapabilityEmbed) || models[1].Can(CapabilityChat) {
+		t.Errorf("Expected an embedding model, got %+v", models[1])
+	}
+	if len(models[2].Capabilities) != 0 {
+		t.Errorf("Expected no capabilities for whisper-1, got %v", models[2].Capabilities)
+	}
+}
+
+func TestAnthropicListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "sk-ant-test" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("Missing Anthropic headers: %v", r.Header)
+		}
+		switch r.URL.Query().Get("after_id") {
+		case "":
+			fmt.Fprint(w, `{"data":[{"type":"model","id":"claude-sonnet-4-20250514"}],"has_more":true,"last_id":"claude-sonnet-4-20250514"}`)
+		case "claude-sonnet-4-20250514":
+			fmt.Fprint(w, `{"data":[{"type":"model","id":"claude-future-1","max_input_tokens":500000,"max_tokens":64000}],"has_more":false}`)
+		default:
+			t.Errorf("Unexpected page %s", r.URL.RawQuery)
+		}
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("sk-ant-test", "")
+	p.baseURL = server.URL
+
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models from two pages, got %d", len(models))
+	}
+	if models[0].ContextLength != 200000 || !models[0].Can(CapabilityTools) {
+		t.Errorf("Unexpected metadata for %s: %+v", models[0].Name, models[0])
+	}
+	if models[1].ContextLength != 500000 || models[1].MaxOutput != 64000 {
+		t.Errorf("Reported limits should win, got %+v", models[1])
+	}
+}
+
+func TestDiscoverModelsCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"data":[{"id":"gpt-4o"}]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("sk-test", "")
+	p.baseURL = server.URL
+	cache := NewModelCache(t.TempDir(), time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		models, err := DiscoverModels(ctx, p, cache)
+		if err != nil {
+			t.Fatalf("DiscoverModels failed: %v", err)
+		}
+		if len(models) != 1 || models[0].Name != "gpt-4o" || !models[0].Can(CapabilityChat) {
+			t.Fatalf("DiscoverModels() = %+v", models)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the second lookup to be cached, got %d requests", calls)
+	}
+
+	now = now.Add(2 * time.Hour)
+	DiscoverModels(ctx, p, cache)
+	if calls != 2 {
+		t.Errorf("Expected an expired list to be fetched again, got %d requests", calls)
+	}
+
+	other := NewOpenAIProvider("sk-test", "")
+	other.baseURL = server.URL + "/other"
+	DiscoverModels(ctx, other, cache)
+	if calls != 3 {
+		t.Errorf("Expected another endpoint not to share the list, got %d requests", calls)
+	}
+
+	cache.Forget(other)
+	DiscoverModels(ctx, other, cache)
+	if calls != 4 {
+		t.Errorf("Expected a forgotten list to be fetched again, got %d requests", calls)
+	}
+}
+
+func TestCheckModel(t *testing.T) {
+	models := []Model{
+		{Name: "gpt-4o"},
+		{Name: "gpt-4o-mini"},
+		{Name: "gpt-4o-2024-08-06"},
+		{Name: "claude-3-5-sonnet-20241022"},
+		{Name: "llama3.2:latest"},
+	}
+	for _, model := range []string{"gpt-4o", "llama3.2", "claude-3-5-sonnet", "claude-3-5-sonnet-latest"} {
+		if err := CheckModel(models, "test", model); err != nil {
+			t.Errorf("CheckModel(%q) = %v, want nil", model, err)
+		}
+	}
+
+	err := CheckModel(models, "openai", "gpt-4o-mni")
+	if !errors.Is(err, ErrUnknownModel) {
+		t.Fatalf("CheckModel() = %v, want ErrUnknownModel", err)
+	}
+	if !strings.Contains(err.Error(), `did you mean "gpt-4o-mini"`) {
+		t.Errorf("error %q should suggest gpt-4o-mini first", err)
+	}
+	if err := CheckModel(models, "openai", "gpt-4"); err == nil {
+		t.Error("gpt-4 should not match gpt-4o")
+	}
+	if err := CheckModel(nil, "ollama", "llama3.2"); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("CheckModel() with no models = %v, want ErrUnknownModel", err)
+	}
+}
+
+func TestDetectHostedModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") == "sk-ant-revoked" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[{"id":"text-embedding-3-small"},{"id":"o3-mini"},{"id":"gpt-4o"}]}`)
+	}))
+	defer server.Close()
+
+	d := NewDetector("http://invalid:99999", "sk-test", "sk-ant-revoked")
+	d.hostedURLs = map[ProviderType]string{ProviderOpenAI: server.URL, ProviderAnthropic: server.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	results := d.DetectAll(ctx)
+	openAI, anthropic := results[1], results[2]
+	if !openAI.Available || !reflect.DeepEqual(openAI.Models, []string{"gpt-4o", "o3-mini"}) {
+		t.Errorf("OpenAI result = %+v, want chat models with the default first", openAI)
+	}
+	if anthropic.Available {
+		t.Errorf("Anthropic with a rejected key should be unavailable: %+v", anthropic)
+	}
+}
@@ -15,6 +15,7 @@ type OllamaProvider struct {
 	baseURL string
 	model   string
 	client  *http.Client
+	retry   RetryPolicy
 }
 
 // NewOllamaProvider creates a new Ollama provider
@@ -28,9 +29,15 @@ func NewOllamaProvider(baseURL, model string) *OllamaProvider {
 		client: &http.Client{
 			Timeout: 120 * time.Second,
 		},
+		retry: DefaultRetryPolicy(),
 	}
 }
 
+// SetRetryPolicy sets how transient failures are retried
+func (o *OllamaProvider) SetRetryPolicy(policy RetryPolicy) {
+	o.retry = policy
+}
+
 func (o *OllamaProvider) Name() string {
 	return "ollama"
 }
@@ -75,53 +82,249 @@ func (o *OllamaProvider) Models(ctx context.Context) ([]string, error) {
 	return models, nil
 }
 
-func (o *OllamaProvider) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
-	model := opts.Model
+func (o *OllamaProvider) endpoint() string {
+	return o.baseURL
+}
+
+func (o *OllamaProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	resp, err := o.generate(ctx, o.client, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result ollamaGenerateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	out := &GenerateResponse{
+		Text:         result.text(),
+		FinishReason: result.DoneReason,
+		Model:        result.Model,
+		Usage:        result.usage(),
+	}
+	// Ollama neither identifies tool calls nor reports them as a finish reason
+	for i, c := range result.Message.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{
+			ID:        fmt.Sprintf("call_%d", i),
+			Name:      c.Function.Name,
+			Arguments: c.Function.Arguments,
+		})
+		out.FinishReason = FinishToolCalls
+	}
+	return out, nil
+}
+
+// GenerateStream streams a completion from Ollama's NDJSON response
+func (o *OllamaProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	resp, err := o.generate(ctx, streamClient, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan GenerateChunk)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var line ollamaGenerateResponse
+			if err := dec.Decode(&line); err != nil {
+				if err == io.EOF {
+					err = fmt.Errorf("ollama stream ended unexpectedly")
+				}
+				sendChunk(ctx, ch, GenerateChunk{Error: err})
+				return
+			}
+			if line.Error != "" {
+				sendChunk(ctx, ch, GenerateChunk{Error: fmt.Errorf("ollama error: %s", line.Error)})
+				return
+			}
+			if line.Done {
+				if line.text() != "" && !sendChunk(ctx, ch, GenerateChunk{Text: line.text()}) {
+					return
+				}
+				sendChunk(ctx, ch, GenerateChunk{
+					Done:         true,
+					FinishReason: line.DoneReason,
+					Model:        line.Model,
+					Usage:        line.usage(),
+				})
+				return
+			}
+			if !sendChunk(ctx, ch, GenerateChunk{Text: line.text()}) {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// ollamaGenerateResponse is a /api/generate or /api/chat response or
+// stream line. Generate answers in Response, chat in Message.
+type ollamaGenerateResponse struct {
+	Model           string        `json:"model"`
+	Response        string        `json:"response"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// ollamaMessage is a chat message in Ollama's format
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"` // JSON object
+	} `json:"function"`
+}
+
+// ollamaMessages converts the system prompt and turns of req to Ollama's
+// format
+func ollamaMessages(req GenerateRequest) []ollamaMessage {
+	messages := []ollamaMessage{}
+	if req.System != "" {
+		messages = append(messages, ollamaMessage{Role: RoleSystem, Content: req.System})
+	}
+	for _, m := range requestMessages(req) {
+		msg := ollamaMessage{Role: m.Role, Content: m.Content, ToolName: m.ToolName}
+		for _, c := range m.ToolCalls {
+			var call ollamaToolCall
+			call.Function.Name = c.Name
+			call.Function.Arguments = c.Arguments
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func (r ollamaGenerateResponse) text() string {
+	return r.Response + r.Message.Content
+}
+
+func (r ollamaGenerateResponse) usage() Usage {
+	return Usage{PromptTokens: r.PromptEvalCount, CompletionTokens: r.EvalCount}
+}
+
+// generate posts to /api/generate, or to /api/chat for conversations and
+// tool use, and returns the response on HTTP 200, retrying transient
+// failures
+func (o *OllamaProvider) generate(ctx context.Context, client *http.Client, req GenerateRequest, stream bool) (*http.Response, error) {
+	model := req.Model
 	if model == "" {
 		model = o.model
 	}
 
 	reqBody := map[string]interface{}{
 		"model":  model,
-		"prompt": prompt,
-		"stream": false,
+		"stream": stream,
 		"options": map[string]interface{}{
-			"temperature": opts.Temperature,
-			"num_predict": opts.MaxTokens,
+			"temperature": req.Temperature,
+			"num_predict": req.MaxTokens,
 		},
 	}
-	if opts.SystemPrompt != "" {
-		reqBody["system"] = opts.SystemPrompt
+
+	// Conversations and tools go to /api/chat, single prompts to /api/generate
+	endpoint := "/api/generate"
+	if len(req.Messages) > 0 || len(req.Tools) > 0 {
+		endpoint = "/api/chat"
+		reqBody["messages"] = ollamaMessages(req)
+		if len(req.Tools) > 0 {
+			reqBody["tools"] = openAITools(req.Tools)
+		}
+	} else {
+		reqBody["prompt"] = req.Prompt
+		if req.System != "" {
+			reqBody["system"] = req.System
+		}
+	}
+	if len(req.Stop) > 0 {
+		reqBody["options"].(map[string]interface{})["stop"] = req.Stop
+	}
+	// Ollama truncates prompts longer than its default context silently
+	if req.ContextWindow > 0 {
+		reqBody["options"].(map[string]interface{})["num_ctx"] = req.ContextWindow
+	}
+	if req.Schema != nil {
+		reqBody["format"] = req.Schema.Schema
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/generate", bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+endpoint, bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := o.client.Do(req)
+	return doRequest(client, httpReq, "ollama", o.retry)
+}
+
+// ollamaEmbedModel embeds text when no model is requested; the chat model
+// usually cannot
+const ollamaEmbedModel = "nomic-embed-text"
+
+// Embed embeds the inputs with Ollama's /api/embed endpoint
+func (o *OllamaProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = ollamaEmbedModel
+	}
+	reqBody := map[string]interface{}{
+		"model": model,
+		"input": req.Input,
+	}
+	if req.Dimensions > 0 {
+		reqBody["dimensions"] = req.Dimensions
+	}
+	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("ollama request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama error %d: %s", resp.StatusCode, string(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/api/embed", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := doRequest(o.client, httpReq, "ollama", o.retry)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var result struct {
-		Response string `json:"response"`
+		Model           string      `json:"model"`
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return nil, err
 	}
-
-	return result.Response, nil
+	dims, err := dimensions(result.Embeddings)
+	if err != nil {
+		return nil, fmt.Errorf("ollama: %w", err)
+	}
+	return &EmbedResponse{
+		Embeddings: result.Embeddings,
+		Model:      result.Model,
+		Dimensions: dims,
+		Usage:      Usage{PromptTokens: result.PromptEvalCount},
+	}, nil
 }
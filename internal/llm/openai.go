@@ -7,34 +7,47 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 // OpenAIProvider implements Provider for OpenAI
 type OpenAIProvider struct {
-	apiKey  string
-	model   string
-	baseURL string
-	client  *http.Client
+	name       string
+	apiKey     string
+	model      string
+	embedModel string // Model for embeddings when none is requested
+	baseURL    string
+	headers    map[string]string
+	client     *http.Client
+	retry      RetryPolicy
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
 func NewOpenAIProvider(apiKey, model string) *OpenAIProvider {
 	if model == "" {
-		model = "gpt-4"
+		model = "gpt-4o"
 	}
 	return &OpenAIProvider{
-		apiKey:  apiKey,
-		model:   model,
-		baseURL: "https://api.openai.com/v1",
+		name:       "openai",
+		apiKey:     apiKey,
+		model:      model,
+		embedModel: "text-embedding-3-small",
+		baseURL:    "https://api.openai.com/v1",
 		client: &http.Client{
 			Timeout: 120 * time.Second,
 		},
+		retry: DefaultRetryPolicy(),
 	}
 }
 
+// SetRetryPolicy sets how transient failures are retried
+func (o *OpenAIProvider) SetRetryPolicy(policy RetryPolicy) {
+	o.retry = policy
+}
+
 func (o *OpenAIProvider) Name() string {
-	return "openai"
+	return o.name
 }
 
 func (o *OpenAIProvider) Available(ctx context.Context) bool {
@@ -42,74 +55,367 @@ func (o *OpenAIProvider) Available(ctx context.Context) bool {
 }
 
 func (o *OpenAIProvider) Models(ctx context.Context) ([]string, error) {
-	return []string{"gpt-4", "gpt-4-turbo", "gpt-3.5-turbo"}, nil
+	models, err := o.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	return names, nil
 }
 
-func (o *OpenAIProvider) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
-	model := opts.Model
-	if model == "" {
-		model = o.model
+// ListModels queries /models. OpenAI reports only model names, so context
+// windows and prices come from the known tables and capabilities are
+// inferred from the model family.
+func (o *OpenAIProvider) ListModels(ctx context.Context) ([]Model, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", o.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	o.setHeaders(req)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(o.name, resp)
+	}
+
+	var result struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	models := make([]Model, len(result.Data))
+	for i, m := range result.Data {
+		models[i] = describeModel(m.ID, openAICapabilities(m.ID)...)
+	}
+	return models, nil
+}
+
+// openAICapabilities infers what an OpenAI model can do from its name.
+// Audio, image and moderation models are listed without capabilities.
+func openAICapabilities(id string) []Capability {
+	if strings.Contains(id, "embedding") {
+		return []Capability{CapabilityEmbed}
+	}
+	for _, special := range []string{"audio", "realtime", "tts", "transcribe", "image", "search", "instruct"} {
+		if strings.Contains(id, special) {
+			return nil
+		}
+	}
+	switch {
+	case strings.HasPrefix(id, "gpt-3.5"):
+		return []Capability{CapabilityChat, CapabilityTools}
+	case strings.HasPrefix(id, "gpt-4-") && !strings.HasPrefix(id, "gpt-4-turbo"):
+		return []Capability{CapabilityChat, CapabilityTools}
+	case id == "gpt-4":
+		return []Capability{CapabilityChat, CapabilityTools}
+	case strings.HasPrefix(id, "gpt-"), strings.HasPrefix(id, "chatgpt-"),
+		strings.HasPrefix(id, "o1"), strings.HasPrefix(id, "o3"), strings.HasPrefix(id, "o4"):
+		return []Capability{CapabilityChat, CapabilityTools, CapabilityVision}
+	}
+	return nil
+}
+
+func (o *OpenAIProvider) endpoint() string {
+	return o.baseURL
+}
+
+func (o *OpenAIProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	resp, err := o.chatCompletion(ctx, o.client, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no response from %s", o.name)
+	}
+
+	message := result.Choices[0].Message
+	var calls []ToolCall
+	for _, c := range message.ToolCalls {
+		args := json.RawMessage(c.Function.Arguments)
+		if len(args) == 0 {
+			args = json.RawMessage("{}")
+		}
+		calls = append(calls, ToolCall{ID: c.ID, Name: c.Function.Name, Arguments: args})
+	}
+
+	return &GenerateResponse{
+		Text:         message.Content,
+		FinishReason: result.Choices[0].FinishReason,
+		Model:        result.Model,
+		Usage:        result.Usage.usage(),
+		ToolCalls:    calls,
+	}, nil
+}
+
+// GenerateStream streams a completion from OpenAI's server-sent events
+func (o *OpenAIProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	resp, err := o.chatCompletion(ctx, streamClient, req, true)
+	if err != nil {
+		return nil, err
 	}
 
-	messages := []map[string]string{}
-	if opts.SystemPrompt != "" {
-		messages = append(messages, map[string]string{
-			"role":    "system",
-			"content": opts.SystemPrompt,
+	ch := make(chan GenerateChunk)
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		final := GenerateChunk{Done: true}
+		done := false
+		err := readSSE(resp.Body, func(event, data string) error {
+			if data == "[DONE]" {
+				done = true
+				sendChunk(ctx, ch, final)
+				return io.EOF
+			}
+			var chunk openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				return fmt.Errorf("invalid %s stream event: %w", o.name, err)
+			}
+			if chunk.Model != "" {
+				final.Model = chunk.Model
+			}
+			if chunk.Usage != nil {
+				final.Usage = chunk.Usage.usage()
+			}
+			if len(chunk.Choices) == 0 {
+				return nil
+			}
+			if reason := chunk.Choices[0].FinishReason; reason != "" {
+				final.FinishReason = reason
+			}
+			if text := chunk.Choices[0].Delta.Content; text != "" && !sendChunk(ctx, ch, GenerateChunk{Text: text}) {
+				return ctx.Err()
+			}
+			return nil
 		})
+		if err == io.EOF {
+			return
+		}
+		if err == nil && !done {
+			err = fmt.Errorf("%s stream ended unexpectedly", o.name)
+		}
+		if err != nil {
+			sendChunk(ctx, ch, GenerateChunk{Error: err})
+		}
+	}()
+	return ch, nil
+}
+
+// openAIChatResponse is a chat completion response or stream chunk
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   string           `json:"content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// openAIMessage is a chat message in OpenAI's format
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON encoded as a string
+	} `json:"function"`
+}
+
+// openAIMessages converts the system prompt and turns of req to OpenAI's
+// format
+func openAIMessages(req GenerateRequest) []openAIMessage {
+	messages := []openAIMessage{}
+	if req.System != "" {
+		messages = append(messages, openAIMessage{Role: RoleSystem, Content: req.System})
+	}
+	for _, m := range requestMessages(req) {
+		msg := openAIMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for _, c := range m.ToolCalls {
+			call := openAIToolCall{ID: c.ID, Type: "function"}
+			call.Function.Name = c.Name
+			call.Function.Arguments = string(c.Arguments)
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// openAITools converts tools to OpenAI's function tool format
+func openAITools(tools []ToolSpec) []map[string]interface{} {
+	out := make([]map[string]interface{}, len(tools))
+	for i, t := range tools {
+		out[i] = map[string]interface{}{"type": "function", "function": t}
+	}
+	return out
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u *openAIUsage) usage() Usage {
+	if u == nil {
+		return Usage{}
+	}
+	return Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens}
+}
+
+// chatCompletion posts to /chat/completions and returns the response on
+// HTTP 200, retrying transient failures
+func (o *OpenAIProvider) chatCompletion(ctx context.Context, client *http.Client, req GenerateRequest, stream bool) (*http.Response, error) {
+	model := req.Model
+	if model == "" {
+		model = o.model
 	}
-	messages = append(messages, map[string]string{
-		"role":    "user",
-		"content": prompt,
-	})
 
 	reqBody := map[string]interface{}{
 		"model":       model,
-		"messages":    messages,
-		"temperature": opts.Temperature,
-		"max_tokens":  opts.MaxTokens,
+		"messages":    openAIMessages(req),
+		"temperature": req.Temperature,
+		"max_tokens":  req.MaxTokens,
+	}
+	if len(req.Stop) > 0 {
+		reqBody["stop"] = req.Stop
+	}
+	if len(req.Tools) > 0 {
+		reqBody["tools"] = openAITools(req.Tools)
 	}
-	if len(opts.Stop) > 0 {
-		reqBody["stop"] = opts.Stop
+	if req.Schema != nil {
+		reqBody["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   req.Schema.Name,
+				"schema": req.Schema.Schema,
+			},
+		}
+	}
+	if stream {
+		reqBody["stream"] = true
+		reqBody["stream_options"] = map[string]interface{}{"include_usage": true}
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	o.setHeaders(httpReq)
 
-	resp, err := o.client.Do(req)
+	return doRequest(client, httpReq, o.name, o.retry)
+}
+
+// Embed embeds the inputs with the /embeddings endpoint
+func (o *OpenAIProvider) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = o.embedModel
+	}
+	reqBody := map[string]interface{}{
+		"input":           req.Input,
+		"encoding_format": "float",
+	}
+	if model != "" {
+		reqBody["model"] = model
+	}
+	if req.Dimensions > 0 {
+		reqBody["dimensions"] = req.Dimensions
+	}
+	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("openai request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("openai error %d: %s", resp.StatusCode, string(body))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/embeddings", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	o.setHeaders(httpReq)
+
+	resp, err := doRequest(o.client, httpReq, o.name, o.retry)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
 	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+		Model string `json:"model"`
+		Data  []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage *openAIUsage `json:"usage"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if len(result.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+	// Data is not guaranteed to be in input order
+	embeddings := make([][]float32, len(req.Input))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(embeddings) {
+			return nil, fmt.Errorf("%s returned an embedding for input %d of %d", o.name, d.Index, len(embeddings))
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	dims, err := dimensions(embeddings)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", o.name, err)
 	}
+	return &EmbedResponse{
+		Embeddings: embeddings,
+		Model:      result.Model,
+		Dimensions: dims,
+		Usage:      result.Usage.usage(),
+	}, nil
+}
 
-	return result.Choices[0].Message.Content, nil
+// setHeaders adds authentication and any extra headers to req
+func (o *OpenAIProvider) setHeaders(req *http.Request) {
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	for k, v := range o.headers {
+		req.Header.Set(k, v)
+	}
 }
@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// OpenAICompatibleProvider implements Provider for self-hosted servers that
+// speak the OpenAI chat-completions protocol, such as llama.cpp server, vLLM
+// and LM Studio. The API key is optional and the model defaults to the first
+// one the server reports. Embeddings use the configured model, since these
+// servers usually serve one model.
+type OpenAICompatibleProvider struct {
+	*OpenAIProvider
+
+	mu     sync.Mutex
+	models []Model
+}
+
+// NewOpenAICompatibleProvider creates a provider for the server at baseURL.
+// A missing /v1 suffix is added.
+func NewOpenAICompatibleProvider(baseURL, apiKey, model string, headers map[string]string) *OpenAICompatibleProvider {
+	p := NewOpenAIProvider(apiKey, "")
+	p.name = "openai-compatible"
+	p.model = model
+	p.embedModel = model
+	p.baseURL = normalizeOpenAIBaseURL(baseURL)
+	p.headers = headers
+	return &OpenAICompatibleProvider{OpenAIProvider: p}
+}
+
+// normalizeOpenAIBaseURL trims trailing slashes and ensures the /v1 prefix
+func normalizeOpenAIBaseURL(baseURL string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+	return baseURL
+}
+
+func (c *OpenAICompatibleProvider) Available(ctx context.Context) bool {
+	_, err := c.ListModels(ctx)
+	return err == nil
+}
+
+func (c *OpenAICompatibleProvider) Models(ctx context.Context) ([]string, error) {
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	return names, nil
+}
+
+// ListModels queries /v1/models. Context lengths are filled in when the
+// server reports them (vLLM's max_model_len, LM Studio's context_length).
+// A successful result is cached for the life of the provider.
+func (c *OpenAICompatibleProvider) ListModels(ctx context.Context) ([]Model, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.models != nil {
+		return c.models, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setHeaders(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(c.name, resp)
+	}
+
+	var result struct {
+		Data []struct {
+			ID            string `json:"id"`
+			MaxModelLen   int    `json:"max_model_len"`
+			ContextLength int    `json:"context_length"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	models := make([]Model, len(result.Data))
+	for i, m := range result.Data {
+		models[i] = Model{Name: m.ID, ContextLength: max(m.MaxModelLen, m.ContextLength)}
+	}
+	c.models = models
+	return models, nil
+}
+
+func (c *OpenAICompatibleProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	req, err := c.withModel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return c.OpenAIProvider.Generate(ctx, req)
+}
+
+func (c *OpenAICompatibleProvider) GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error) {
+	req, err := c.withModel(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return c.OpenAIProvider.GenerateStream(ctx, req)
+}
+
+// withModel fills in the request model from the configured model or, when
+// none is set, the first model the server serves
+func (c *OpenAICompatibleProvider) withModel(ctx context.Context, req GenerateRequest) (GenerateRequest, error) {
+	if req.Model != "" || c.model != "" {
+		return req, nil
+	}
+
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return req, err
+	}
+	if len(models) == 0 {
+		return req, fmt.Errorf("%s server at %s reports no models", c.name, c.baseURL)
+	}
+	req.Model = models[0].Name
+	return req, nil
+}
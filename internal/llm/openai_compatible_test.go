@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newCompatibleServer serves /v1/models and /v1/chat/completions the way
+// llama.cpp server and vLLM do
+func newCompatibleServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Unexpected Authorization header without an API key")
+		}
+		if r.Header.Get("X-Team") != "factory" {
+			t.Errorf("Missing custom header")
+		}
+
+		switch r.URL.Path {
+		case "/v1/models":
+			fmt.Fprint(w, `{"data":[{"id":"qwen2.5-coder-7b","max_model_len":32768},{"id":"llama-3.1-8b"}]}`)
+		case "/v1/chat/completions":
+			var body struct {
+				Model string `json:"model"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprintf(w, `{"model":%q,"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`, body.Model)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestOpenAICompatibleProvider(t *testing.T) {
+	server := newCompatibleServer(t)
+	defer server.Close()
+
+	p := NewOpenAICompatibleProvider(server.URL+"/", "", "", map[string]string{"X-Team": "factory"})
+	ctx := context.Background()
+
+	if !p.Available(ctx) {
+		t.Fatal("Available() should be true when /v1/models responds")
+	}
+
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 2 || models[0].ContextLength != 32768 {
+		t.Errorf("ListModels() = %+v", models)
+	}
+
+	resp, err := p.Generate(ctx, GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Model != "qwen2.5-coder-7b" {
+		t.Errorf("Expected the first served model to be used, got %q", resp.Model)
+	}
+
+	resp, err = p.Generate(ctx, GenerateRequest{Prompt: "hi", Model: "llama-3.1-8b"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Model != "llama-3.1-8b" {
+		t.Errorf("Expected the requested model, got %q", resp.Model)
+	}
+}
+
+func TestNormalizeOpenAIBaseURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"http://localhost:8080":     "http://localhost:8080/v1",
+		"http://localhost:8080/":    "http://localhost:8080/v1",
+		"http://localhost:1234/v1":  "http://localhost:1234/v1",
+		"http://localhost:1234/v1/": "http://localhost:1234/v1",
+	} {
+		if got := normalizeOpenAIBaseURL(in); got != want {
+			t.Errorf("normalizeOpenAIBaseURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDetectOpenAICompatible(t *testing.T) {
+	server := newCompatibleServer(t)
+	defer server.Close()
+
+	d := NewDetector("http://invalid:99999", "sk-test", "")
+	d.SetOpenAICompatible(server.URL, "", map[string]string{"X-Team": "factory"})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result := d.Detect(ctx)
+	if result.ProviderType != ProviderOpenAICompatible {
+		t.Fatalf("Detect() = %+v, want the OpenAI-compatible server ahead of OpenAI", result)
+	}
+	if len(result.Models) != 2 {
+		t.Errorf("Models = %v", result.Models)
+	}
+
+	provider, err := d.ProviderFor(result, result.Models[1])
+	if err != nil {
+		t.Fatalf("ProviderFor failed: %v", err)
+	}
+	resp, err := provider.Generate(ctx, GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Model != "llama-3.1-8b" {
+		t.Errorf("Model = %q, want llama-3.1-8b", resp.Model)
+	}
+}
@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	openRouterBaseURL      = "https://openrouter.ai/api/v1"
+	openRouterDefaultModel = "openai/gpt-4o-mini"
+
+	// Attribution headers identify the app on openrouter.ai
+	openRouterReferer = "https://github.com/ssdajoker/Code-Factory"
+	openRouterTitle   = "Code Factory"
+)
+
+// OpenRouterProvider implements Provider for OpenRouter, which serves
+// models from many vendors over the OpenAI chat-completions protocol.
+// Model names are namespaced as vendor/model, e.g. anthropic/claude-3.5-sonnet.
+type OpenRouterProvider struct {
+	*OpenAIProvider
+
+	mu     sync.Mutex
+	models []Model
+}
+
+// NewOpenRouterProvider creates a new OpenRouter provider
+func NewOpenRouterProvider(apiKey, model string) *OpenRouterProvider {
+	if model == "" {
+		model = openRouterDefaultModel
+	}
+	p := NewOpenAIProvider(apiKey, model)
+	p.name = "openrouter"
+	p.embedModel = "openai/text-embedding-3-small"
+	p.baseURL = openRouterBaseURL
+	p.headers = map[string]string{
+		"HTTP-Referer": openRouterReferer,
+		"X-Title":      openRouterTitle,
+	}
+	return &OpenRouterProvider{OpenAIProvider: p}
+}
+
+// ValidateOpenRouterModel checks that model uses the vendor/model namespace
+func ValidateOpenRouterModel(model string) error {
+	vendor, name, ok := strings.Cut(model, "/")
+	if !ok || vendor == "" || name == "" {
+		return fmt.Errorf("OpenRouter model %q must be namespaced as vendor/model, e.g. %s", model, openRouterDefaultModel)
+	}
+	return nil
+}
+
+func (o *OpenRouterProvider) Models(ctx context.Context) ([]string, error) {
+	models, err := o.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.Name
+	}
+	return names, nil
+}
+
+// ListModels fetches the OpenRouter catalogue with context lengths,
+// output limits, capabilities and per-token pricing. The result is cached
+// for the life of the provider.
+func (o *OpenRouterProvider) ListModels(ctx context.Context) ([]Model, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.models != nil {
+		return o.models, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", o.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	o.setHeaders(req)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(o.name, resp)
+	}
+
+	var result struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+			Pricing       struct {
+				Prompt     string `json:"prompt"`
+				Completion string `json:"completion"`
+			} `json:"pricing"`
+			Architecture struct {
+				InputModalities []string `json:"input_modalities"`
+			} `json:"architecture"`
+			TopProvider struct {
+				MaxCompletionTokens int `json:"max_completion_tokens"`
+			} `json:"top_provider"`
+			SupportedParameters []string `json:"supported_parameters"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	models := make([]Model, len(result.Data))
+	for i, m := range result.Data {
+		capabilities := []Capability{CapabilityChat}
+		if slices.Contains(m.SupportedParameters, "tools") {
+			capabilities = append(capabilities, CapabilityTools)
+		}
+		if slices.Contains(m.Architecture.InputModalities, "image") {
+			capabilities = append(capabilities, CapabilityVision)
+		}
+		models[i] = Model{
+			Name:          m.ID,
+			ContextLength: m.ContextLength,
+			MaxOutput:     m.TopProvider.MaxCompletionTokens,
+			Capabilities:  capabilities,
+			Pricing: Pricing{
+				Prompt:     parsePrice(m.Pricing.Prompt),
+				Completion: parsePrice(m.Pricing.Completion),
+			},
+		}
+	}
+	o.models = models
+	return models, nil
+}
+
+// parsePrice parses an OpenRouter price string. Unparseable and negative
+// (variable) prices are reported as zero.
+func parsePrice(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
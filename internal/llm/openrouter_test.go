@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenRouterGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-or-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if r.Header.Get("HTTP-Referer") == "" || r.Header.Get("X-Title") == "" {
+			t.Error("Missing attribution headers")
+		}
+		fmt.Fprint(w, `{"model":"anthropic/claude-3.5-sonnet","choices":[{"message":{"content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenRouterProvider("sk-or-test", "anthropic/claude-3.5-sonnet")
+	p.baseURL = server.URL
+
+	resp, err := p.Generate(context.Background(), GenerateRequest{Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Text != "hi" || resp.Model != "anthropic/claude-3.5-sonnet" {
+		t.Errorf("Generate() = %+v", resp)
+	}
+	if p.Name() != "openrouter" {
+		t.Errorf("Name() = %v, want openrouter", p.Name())
+	}
+}
+
+func TestOpenRouterListModels(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"data":[
+			{"id":"openai/gpt-4o","context_length":128000,"pricing":{"prompt":"0.0000025","completion":"0.00001"}},
+			{"id":"meta-llama/llama-3.1-8b-instruct:free","context_length":131072,"pricing":{"prompt":"0","completion":"0"}},
+			{"id":"openrouter/auto","context_length":2000000,"pricing":{"prompt":"-1","completion":"-1"}}
+		]}`)
+	}))
+	defer server.Close()
+
+	p := NewOpenRouterProvider("sk-or-test", "")
+	p.baseURL = server.URL
+	ctx := context.Background()
+
+	models, err := p.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 3 {
+		t.Fatalf("Expected 3 models, got %d", len(models))
+	}
+	if models[0].ContextLength != 128000 || models[0].Pricing.Prompt != 0.0000025 {
+		t.Errorf("Unexpected metadata: %+v", models[0])
+	}
+	if models[2].Pricing.Prompt != 0 {
+		t.Errorf("Variable pricing should parse as 0, got %v", models[2].Pricing.Prompt)
+	}
+
+	cost := models[0].Pricing.Cost(Usage{PromptTokens: 1000, CompletionTokens: 100})
+	if math.Abs(cost-0.0035) > 1e-9 {
+		t.Errorf("Cost() = %v, want 0.0035", cost)
+	}
+
+	names, _ := p.Models(ctx)
+	if calls != 1 {
+		t.Errorf("Expected the model list to be cached, got %d requests", calls)
+	}
+
+	SortModelsByPrice(models)
+	if models[2].Name != "openai/gpt-4o" {
+		t.Errorf("Expected gpt-4o to sort last, got %v (names %v)", models[2].Name, names)
+	}
+}
+
+func TestValidateOpenRouterModel(t *testing.T) {
+	for model, wantErr := range map[string]bool{
+		"openai/gpt-4o":     false,
+		"meta-llama/x:free": false,
+		"gpt-4o":            true,
+		"/gpt-4o":           true,
+		"openai/":           true,
+	} {
+		if err := ValidateOpenRouterModel(model); (err != nil) != wantErr {
+			t.Errorf("ValidateOpenRouterModel(%q) error = %v, wantErr %v", model, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package llm
+
+import "strings"
+
+// perMillion converts a price per million tokens to a price per token
+func perMillion(prompt, completion float64) Pricing {
+	return Pricing{Prompt: prompt / 1e6, Completion: completion / 1e6}
+}
+
+// prices maps hosted model name prefixes to their list prices. Longer
+// prefixes are matched first. Local models are free and not listed.
+var prices = map[string]Pricing{
+	"gpt-4o-mini":            perMillion(0.15, 0.60),
+	"gpt-4o":                 perMillion(2.50, 10.00),
+	"gpt-4.1-nano":           perMillion(0.10, 0.40),
+	"gpt-4.1-mini":           perMillion(0.40, 1.60),
+	"gpt-4.1":                perMillion(2.00, 8.00),
+	"gpt-4-turbo":            perMillion(10.00, 30.00),
+	"gpt-4":                  perMillion(30.00, 60.00),
+	"gpt-3.5-turbo":          perMillion(0.50, 1.50),
+	"o1-mini":                perMillion(1.10, 4.40),
+	"o1":                     perMillion(15.00, 60.00),
+	"o3-mini":                perMillion(1.10, 4.40),
+	"text-embedding-3-small": perMillion(0.02, 0),
+	"text-embedding-3-large": perMillion(0.13, 0),
+	"text-embedding-ada-002": perMillion(0.10, 0),
+	"claude-3-haiku":         perMillion(0.25, 1.25),
+	"claude-3-5-haiku":       perMillion(0.80, 4.00),
+	"claude-3.5-haiku":       perMillion(0.80, 4.00),
+	"claude-3-sonnet":        perMillion(3.00, 15.00),
+	"claude-3-5-sonnet":      perMillion(3.00, 15.00),
+	"claude-3.5-sonnet":      perMillion(3.00, 15.00),
+	"claude-3-7-sonnet":      perMillion(3.00, 15.00),
+	"claude-3.7-sonnet":      perMillion(3.00, 15.00),
+	"claude-sonnet-4":        perMillion(3.00, 15.00),
+	"claude-3-opus":          perMillion(15.00, 75.00),
+	"claude-opus-4":          perMillion(15.00, 75.00),
+}
+
+// PriceFor returns the list price of model. OpenRouter names such as
+// anthropic/claude-3.5-sonnet are priced as the vendor's model. The second
+// result is false for models without a known price, such as local models.
+func PriceFor(model string) (Pricing, bool) {
+	model = strings.ToLower(model)
+	if _, name, ok := strings.Cut(model, "/"); ok {
+		model = name
+	}
+	best := ""
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return Pricing{}, false
+	}
+	return prices[best], true
+}
@@ -1,7 +1,6 @@
 package llm
 
 import (
-	"context"
 	"errors"
 	"fmt"
 )
@@ -11,25 +10,27 @@ var (
 	ErrProviderFailed = errors.New("provider request failed")
 )
 
-// Provider is the main interface for LLM providers
-type Provider interface {
-	// Complete generates a completion for the given prompt
-	Complete(ctx context.Context, prompt string, opts Options) (string, error)
-	// Name returns the provider name
-	Name() string
-	// Available checks if the provider is available
-	Available(ctx context.Context) bool
-	// Models returns available models
-	Models(ctx context.Context) ([]string, error)
-}
-
 // Options configures the completion request
 type Options struct {
-	Temperature  float64
-	MaxTokens    int
-	SystemPrompt string
-	Model        string
-	Stop         []string
+	Temperature   float64
+	MaxTokens     int
+	SystemPrompt  string
+	Model         string
+	Stop          []string
+	ContextWindow int // Tokens the prompt and response may use, 0 for the provider's default
+}
+
+// Request converts the options into a GenerateRequest for prompt
+func (o Options) Request(prompt string) GenerateRequest {
+	return GenerateRequest{
+		Prompt:        prompt,
+		System:        o.SystemPrompt,
+		Model:         o.Model,
+		Temperature:   o.Temperature,
+		MaxTokens:     o.MaxTokens,
+		Stop:          o.Stop,
+		ContextWindow: o.ContextWindow,
+	}
 }
 
 // DefaultOptions returns sensible defaults
@@ -45,21 +46,40 @@ func DefaultOptions() Options {
 type ProviderType string
 
 const (
-	ProviderOllama    ProviderType = "ollama"
-	ProviderOpenAI    ProviderType = "openai"
-	ProviderAnthropic ProviderType = "anthropic"
+	ProviderOllama           ProviderType = "ollama"
+	ProviderOpenAI           ProviderType = "openai"
+	ProviderAnthropic        ProviderType = "anthropic"
+	ProviderOpenRouter       ProviderType = "openrouter"
+	ProviderOpenAICompatible ProviderType = "openai-compatible"
 )
 
 // Config holds provider configuration
 type Config struct {
-	Type       ProviderType
-	APIKey     string
-	BaseURL    string
-	Model      string
+	Type        ProviderType
+	APIKey      string
+	BaseURL     string
+	Model       string
+	Headers     map[string]string
+	MaxAttempts int // Attempts per request for transient failures; zero uses the default
 }
 
 // NewProvider creates a provider based on config
 func NewProvider(cfg Config) (Provider, error) {
+	p, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxAttempts > 0 {
+		policy := DefaultRetryPolicy()
+		policy.MaxAttempts = cfg.MaxAttempts
+		if r, ok := p.(interface{ SetRetryPolicy(RetryPolicy) }); ok {
+			r.SetRetryPolicy(policy)
+		}
+	}
+	return p, nil
+}
+
+func newProvider(cfg Config) (Provider, error) {
 	switch cfg.Type {
 	case ProviderOllama:
 		baseURL := cfg.BaseURL
@@ -71,12 +91,39 @@ func NewProvider(cfg Config) (Provider, error) {
 		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("OpenAI API key required")
 		}
-		return NewOpenAIProvider(cfg.APIKey, cfg.Model), nil
+		p := NewOpenAIProvider(cfg.APIKey, cfg.Model)
+		if cfg.BaseURL != "" {
+			p.baseURL = cfg.BaseURL
+		}
+		return p, nil
 	case ProviderAnthropic:
 		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("Anthropic API key required")
 		}
-		return NewAnthropicProvider(cfg.APIKey, cfg.Model), nil
+		p := NewAnthropicProvider(cfg.APIKey, cfg.Model)
+		if cfg.BaseURL != "" {
+			p.baseURL = cfg.BaseURL
+		}
+		return p, nil
+	case ProviderOpenRouter:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("OpenRouter API key required")
+		}
+		if cfg.Model != "" {
+			if err := ValidateOpenRouterModel(cfg.Model); err != nil {
+				return nil, err
+			}
+		}
+		p := NewOpenRouterProvider(cfg.APIKey, cfg.Model)
+		if cfg.BaseURL != "" {
+			p.baseURL = cfg.BaseURL
+		}
+		return p, nil
+	case ProviderOpenAICompatible:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("base URL required for OpenAI-compatible provider")
+		}
+		return NewOpenAICompatibleProvider(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Headers), nil
 	default:
 		return nil, fmt.Errorf("unknown provider type: %s", cfg.Type)
 	}
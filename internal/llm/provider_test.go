@@ -2,6 +2,11 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -14,11 +19,16 @@ type MockProvider struct {
 	err        error
 }
 
-func (m *MockProvider) Complete(ctx context.Context, prompt string, opts Options) (string, error) {
+func (m *MockProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
 	if m.err != nil {
-		return "", m.err
+		return nil, m.err
 	}
-	return m.response, nil
+	return &GenerateResponse{
+		Text:         m.response,
+		FinishReason: FinishStop,
+		Model:        req.Model,
+		Usage:        Usage{PromptTokens: len(req.Prompt), CompletionTokens: len(m.response)},
+	}, nil
 }
 
 func (m *MockProvider) Name() string {
@@ -95,6 +105,39 @@ func TestNewProvider(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "openrouter with key",
+			cfg: Config{
+				Type:   ProviderOpenRouter,
+				APIKey: "sk-or-test",
+				Model:  "anthropic/claude-3.5-sonnet",
+			},
+			wantErr: false,
+		},
+		{
+			name: "openrouter model without vendor",
+			cfg: Config{
+				Type:   ProviderOpenRouter,
+				APIKey: "sk-or-test",
+				Model:  "claude-3.5-sonnet",
+			},
+			wantErr: true,
+		},
+		{
+			name: "openai-compatible without base URL",
+			cfg: Config{
+				Type: ProviderOpenAICompatible,
+			},
+			wantErr: true,
+		},
+		{
+			name: "openai-compatible without key",
+			cfg: Config{
+				Type:    ProviderOpenAICompatible,
+				BaseURL: "http://localhost:8080",
+			},
+			wantErr: false,
+		},
 		{
 			name: "unknown provider",
 			cfg: Config{
@@ -149,8 +192,108 @@ func TestMockProvider(t *testing.T) {
 		t.Errorf("Models() returned %d models, want 2", len(models))
 	}
 
-	resp, _ := mock.Complete(ctx, "test", DefaultOptions())
+	resp, _ := Complete(ctx, mock, "test", DefaultOptions())
 	if resp != "test response" {
 		t.Errorf("Complete() = %v, want 'test response'", resp)
 	}
 }
+
+func TestOptionsRequest(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Model = "m"
+	opts.Stop = []string{"END"}
+
+	req := opts.Request("hello")
+	if req.Prompt != "hello" || req.System != opts.SystemPrompt || req.Model != "m" {
+		t.Errorf("Request() = %+v", req)
+	}
+	if req.Temperature != opts.Temperature || req.MaxTokens != opts.MaxTokens || len(req.Stop) != 1 {
+		t.Errorf("Request() did not copy sampling options: %+v", req)
+	}
+}
+
+func TestOllamaContextWindow(t *testing.T) {
+	var body struct {
+		Options map[string]interface{} `json:"options"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"model":"llama3","response":"hi","done":true}`)
+	}))
+	defer server.Close()
+	p := NewOllamaProvider(server.URL, "llama3")
+
+	opts := DefaultOptions()
+	if _, err := p.Generate(context.Background(), opts.Request("hello")); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, ok := body.Options["num_ctx"]; ok {
+		t.Errorf("num_ctx = %v, want the server default without a context window", body.Options["num_ctx"])
+	}
+
+	opts.ContextWindow = 131072
+	if _, err := p.Generate(context.Background(), opts.Request("hello")); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if body.Options["num_ctx"] != float64(131072) {
+		t.Errorf("num_ctx = %v, want 131072", body.Options["num_ctx"])
+	}
+}
+
+func TestGenerateReportsUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		provider func(url string) Provider
+		want     GenerateResponse
+	}{
+		{
+			name: "ollama",
+			body: `{"model":"llama3","response":"hi","done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":3}`,
+			provider: func(url string) Provider {
+				return NewOllamaProvider(url, "llama3")
+			},
+			want: GenerateResponse{Text: "hi", FinishReason: FinishStop, Model: "llama3", Usage: Usage{12, 3}},
+		},
+		{
+			name: "openai",
+			body: `{"model":"gpt-4o","choices":[{"message":{"content":"hi"},"finish_reason":"length"}],"usage":{"prompt_tokens":9,"completion_tokens":4}}`,
+			provider: func(url string) Provider {
+				p := NewOpenAIProvider("key", "")
+				p.baseURL = url
+				return p
+			},
+			want: GenerateResponse{Text: "hi", FinishReason: FinishLength, Model: "gpt-4o", Usage: Usage{9, 4}},
+		},
+		{
+			name: "anthropic",
+			body: `{"model":"claude-3-haiku","content":[{"type":"text","text":"h"},{"type":"text","text":"i"}],"stop_reason":"end_turn","usage":{"input_tokens":7,"output_tokens":2}}`,
+			provider: func(url string) Provider {
+				p := NewAnthropicProvider("key", "")
+				p.baseURL = url
+				return p
+			},
+			want: GenerateResponse{Text: "hi", FinishReason: FinishStop, Model: "claude-3-haiku", Usage: Usage{7, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			resp, err := tt.provider(server.URL).Generate(context.Background(), DefaultOptions().Request("hello"))
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if !reflect.DeepEqual(*resp, tt.want) {
+				t.Errorf("Generate() = %+v, want %+v", *resp, tt.want)
+			}
+			if resp.Usage.TotalTokens() != tt.want.Usage.PromptTokens+tt.want.Usage.CompletionTokens {
+				t.Errorf("TotalTokens() = %d", resp.Usage.TotalTokens())
+			}
+		})
+	}
+}
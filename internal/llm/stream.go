@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StreamingProvider is implemented by providers that can stream completions
+type StreamingProvider interface {
+	Provider
+	GenerateStream(ctx context.Context, req GenerateRequest) (<-chan GenerateChunk, error)
+}
+
+// streamClient has no overall timeout so long generations are bounded only
+// by the request context
+var streamClient = &http.Client{}
+
+// Stream streams a completion from p. Providers without streaming support
+// produce a single chunk from Generate.
+func Stream(ctx context.Context, p Provider, prompt string, opts Options) (<-chan GenerateChunk, error) {
+	return streamRequest(ctx, p, opts.Request(prompt))
+}
+
+func streamRequest(ctx context.Context, p Provider, req GenerateRequest) (<-chan GenerateChunk, error) {
+	if sp, ok := p.(StreamingProvider); ok {
+		return sp.GenerateStream(ctx, req)
+	}
+
+	resp, err := p.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan GenerateChunk, 2)
+	ch <- GenerateChunk{Text: resp.Text}
+	ch <- GenerateChunk{Done: true, FinishReason: resp.FinishReason, Model: resp.Model, Usage: resp.Usage, Provider: resp.Provider, Cached: resp.Cached, Cost: resp.Cost}
+	close(ch)
+	return ch, nil
+}
+
+// CollectStream drains chunks, calling fn for each piece of text, and
+// returns the full text or the first error
+func CollectStream(chunks <-chan GenerateChunk, fn func(text string)) (string, error) {
+	var sb strings.Builder
+	for chunk := range chunks {
+		if chunk.Error != nil {
+			return sb.String(), chunk.Error
+		}
+		if chunk.Text != "" {
+			sb.WriteString(chunk.Text)
+			if fn != nil {
+				fn(chunk.Text)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// sendChunk delivers c unless ctx is cancelled first
+func sendChunk(ctx context.Context, ch chan<- GenerateChunk, c GenerateChunk) bool {
+	select {
+	case ch <- c:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// readSSE parses a server-sent event stream, calling fn with the event type
+// and data of each event. Reading stops at the first error returned by fn.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
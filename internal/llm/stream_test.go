@@ -0,0 +1,173 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE(t *testing.T) {
+	input := ": keep-alive\n\nevent: first\ndata: one\n\ndata: two\ndata: lines\n\nevent: last\ndata:three\n"
+
+	type event struct{ name, data string }
+	var got []event
+	err := readSSE(strings.NewReader(input), func(name, data string) error {
+		got = append(got, event{name, data})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE failed: %v", err)
+	}
+
+	want := []event{{"first", "one"}, {"", "two\nlines"}, {"last", "three"}}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d events, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Event %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestOllamaGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("Expected stream=true, got %v", body["stream"])
+		}
+		fmt.Fprintln(w, `{"response":"Hello","done":false}`)
+		fmt.Fprintln(w, `{"response":", world","done":false}`)
+		fmt.Fprintln(w, `{"response":"","done":true}`)
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(server.URL, "llama3")
+	assertStream(t, p, "Hello, world")
+}
+
+func TestOpenAIGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hello\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\", world\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("test-key", "")
+	p.baseURL = server.URL
+	assertStream(t, p, "Hello, world")
+}
+
+func TestAnthropicGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"message\":{\"model\":\"claude-3-haiku\",\"usage\":{\"input_tokens\":7}}}\n\n")
+		fmt.Fprint(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"delta\":{\"type\":\"text_delta\",\"text\":\", world\"}}\n\n")
+		fmt.Fprint(w, "event: message_delta\ndata: {\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("test-key", "")
+	p.baseURL = server.URL
+	assertStream(t, p, "Hello, world")
+
+	chunks, err := p.GenerateStream(context.Background(), GenerateRequest{Prompt: "hi", MaxTokens: 16})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	var final GenerateChunk
+	for c := range chunks {
+		final = c
+	}
+	want := GenerateChunk{Done: true, FinishReason: FinishStop, Model: "claude-3-haiku", Usage: Usage{7, 2}}
+	if final != want {
+		t.Errorf("Final chunk = %+v, want %+v", final, want)
+	}
+}
+
+func TestAnthropicGenerateStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("test-key", "")
+	p.baseURL = server.URL
+
+	chunks, err := p.GenerateStream(context.Background(), GenerateRequest{Prompt: "hi", MaxTokens: 16})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	text, err := CollectStream(chunks, nil)
+	if !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected overloaded error, got %v", err)
+	}
+	if text != "Hel" {
+		t.Errorf("Expected partial text 'Hel', got %q", text)
+	}
+}
+
+func TestGenerateStreamHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(server.URL, "missing")
+	if _, err := p.GenerateStream(context.Background(), GenerateRequest{Prompt: "hi"}); err == nil {
+		t.Error("Expected error for HTTP 404")
+	}
+}
+
+func TestStreamFallsBackToGenerate(t *testing.T) {
+	mock := &MockProvider{name: "Mock", available: true, response: "whole response"}
+
+	chunks, err := Stream(context.Background(), mock, "hi", DefaultOptions())
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	var pieces []string
+	text, err := CollectStream(chunks, func(s string) { pieces = append(pieces, s) })
+	if err != nil {
+		t.Fatalf("CollectStream failed: %v", err)
+	}
+	if text != "whole response" || len(pieces) != 1 {
+		t.Errorf("Expected a single chunk with the full response, got %q in %d chunks", text, len(pieces))
+	}
+}
+
+// assertStream checks that p streams want in more than one chunk
+func assertStream(t *testing.T, p StreamingProvider, want string) {
+	t.Helper()
+
+	chunks, err := p.GenerateStream(context.Background(), GenerateRequest{Prompt: "hi", MaxTokens: 16})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	var pieces []string
+	text, err := CollectStream(chunks, func(s string) { pieces = append(pieces, s) })
+	if err != nil {
+		t.Fatalf("CollectStream failed: %v", err)
+	}
+	if text != want {
+		t.Errorf("Expected %q, got %q", want, text)
+	}
+	if len(pieces) < 2 {
+		t.Errorf("Expected multiple chunks, got %d", len(pieces))
+	}
+}
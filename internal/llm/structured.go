@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidOutput is returned when a provider does not produce JSON
+// matching the requested schema, even after repair attempts
+var ErrInvalidOutput = errors.New("invalid structured output")
+
+// maxRepairs is how many times GenerateJSON re-prompts with validation errors
+const maxRepairs = 2
+
+// JSONSchema describes the JSON document a request must produce. Providers
+// with a JSON mode are constrained to it; others are instructed by prompt.
+type JSONSchema struct {
+	Name   string                 // Identifier such as "review", used by OpenAI
+	Schema map[string]interface{} // JSON Schema document
+}
+
+// SchemaFor derives a schema from the struct type of v. Properties are named
+// by their json tags and are required unless tagged omitempty. Fields may
+// carry desc, enum ("a|b|c"), min and max tags.
+func SchemaFor(name string, v interface{}) *JSONSchema {
+	return &JSONSchema{Name: name, Schema: schemaForType(reflect.TypeOf(v))}
+}
+
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			prop := schemaForType(f.Type)
+			if desc := f.Tag.Get("desc"); desc != "" {
+				prop["description"] = desc
+			}
+			if enum := f.Tag.Get("enum"); enum != "" {
+				prop["enum"] = strings.Split(enum, "|")
+			}
+			if min, err := strconv.ParseFloat(f.Tag.Get("min"), 64); err == nil {
+				prop["minimum"] = min
+			}
+			if max, err := strconv.ParseFloat(f.Tag.Get("max"), 64); err == nil {
+				prop["maximum"] = max
+			}
+			properties[name] = prop
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// Validate checks that data is JSON matching the schema and returns every
+// problem found
+func (s *JSONSchema) Validate(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("not valid JSON: %w", err)
+	}
+	var problems []string
+	validate(s.Schema, v, "$", &problems)
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validate(schema map[string]interface{}, v interface{}, path string, problems *[]string) {
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			fail("expected an object")
+			return
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := obj[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					fail("unexpected property %q", name)
+				}
+				continue
+			}
+			validate(prop, obj[name], path+"."+name, problems)
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			fail("expected an array")
+			return
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range arr {
+			validate(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			fail("expected a string")
+			return
+		}
+		if enum := stringList(schema["enum"]); len(enum) > 0 && !contains(enum, s) {
+			fail("%q is not one of %s", s, strings.Join(enum, ", "))
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			fail("expected a number")
+			return
+		}
+		if schema["type"] == "integer" && n != float64(int64(n)) {
+			fail("expected an integer, got %v", n)
+		}
+		if min, ok := schema["minimum"].(float64); ok && n < min {
+			fail("%v is below the minimum %v", n, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && n > max {
+			fail("%v is above the maximum %v", n, max)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("expected a boolean")
+		}
+	}
+}
+
+func stringList(v interface{}) []string {
+	switch l := v.(type) {
+	case []string:
+		return l
+	case []interface{}:
+		out := make([]string, 0, len(l))
+		for _, s := range l {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// extractJSON returns the JSON object in text, dropping Markdown fences and
+// any prose around it
+func extractJSON(text string) string {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return strings.TrimSpace(text)
+	}
+	return text[start : end+1]
+}
+
+// GenerateJSON asks p for JSON matching the schema derived from out, which
+// must be a pointer to a struct, and decodes the answer into out. Invalid
+// answers are sent back with the validation errors for repair. The returned
+// response carries the text of the final answer and the usage and cost of
+// every attempt.
+func GenerateJSON(ctx context.Context, p Provider, name, prompt string, opts Options, out interface{}) (*GenerateResponse, error) {
+	schema := SchemaFor(name, out)
+	schemaJSON, err := json.MarshalIndent(schema.Schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	base := fmt.Sprintf("%s\n\nRespond only with a JSON object matching this JSON Schema, without Markdown fences or commentary:\n%s", prompt, schemaJSON)
+	req := opts.Request(base)
+	req.Schema = schema
+
+	var total *GenerateResponse
+	for attempt := 0; ; attempt++ {
+		resp, err := p.Generate(ctx, req)
+		if err != nil {
+			return total, err
+		}
+		if total == nil {
+			total = &GenerateResponse{}
+		}
+		usage, cost := total.Usage.Add(resp.Usage), total.Cost+resp.Cost
+		*total = *resp
+		total.Usage, total.Cost = usage, cost
+
+		data := extractJSON(resp.Text)
+		verr := schema.Validate([]byte(data))
+		if verr == nil {
+			if err := json.Unmarshal([]byte(data), out); err != nil {
+				return total, fmt.Errorf("%w: %v", ErrInvalidOutput, err)
+			}
+			return total, nil
+		}
+		if attempt == maxRepairs {
+			return total, fmt.Errorf("%w after %d attempts: %v", ErrInvalidOutput, attempt+1, verr)
+		}
+
+		req.Prompt = fmt.Sprintf(`%s
+
+Your previous answer did not match the JSON Schema.
+
+PREVIOUS ANSWER:
+%s
+
+PROBLEMS:
+%v
+
+Respond only with the corrected JSON object.`, base, resp.Text, verr)
+	}
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testFindings struct {
+	Score  int      `json:"score" min:"0" max:"100"`
+	Status string   `json:"status" enum:"pass|fail"`
+	Items  []string `json:"items"`
+	Note   string   `json:"note,omitempty"`
+}
+
+// scriptedProvider answers each request with the next scripted text
+type scriptedProvider struct {
+	MockProvider
+	answers  []string
+	requests []GenerateRequest
+}
+
+func (s *scriptedProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	s.requests = append(s.requests, req)
+	text := s.answers[0]
+	if len(s.answers) > 1 {
+		s.answers = s.answers[1:]
+	}
+	return &GenerateResponse{Text: text, Usage: Usage{PromptTokens: 1, CompletionTokens: 1}}, nil
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := SchemaFor("findings", &testFindings{})
+
+	tests := []struct {
+		name string
+		json string
+		ok   bool
+	}{
+		{"valid", `{"score": 70, "status": "pass", "items": ["a"]}`, true},
+		{"optional field", `{"score": 70, "status": "pass", "items": [], "note": "x"}`, true},
+		{"missing field", `{"score": 70, "status": "pass"}`, false},
+		{"out of range", `{"score": 170, "status": "pass", "items": []}`, false},
+		{"not in enum", `{"score": 70, "status": "maybe", "items": []}`, false},
+		{"wrong type", `{"score": "high", "status": "pass", "items": []}`, false},
+		{"unexpected field", `{"score": 70, "status": "pass", "items": [], "extra": 1}`, false},
+		{"not json", `Score: 70`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.json))
+			if (err == nil) != tt.ok {
+				t.Errorf("Validate() error = %v, want ok=%t", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGenerateJSONRepairs(t *testing.T) {
+	p := &scriptedProvider{answers: []string{
+		`{"score": 170, "status": "pass", "items": []}`,
+		"```json\n{\"score\": 70, \"status\": \"fail\", \"items\": [\"missing logout\"]}\n```",
+	}}
+
+	var out testFindings
+	resp, err := GenerateJSON(context.Background(), p, "findings", "Review it", DefaultOptions(), &out)
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if out.Score != 70 || out.Status != "fail" || len(out.Items) != 1 {
+		t.Errorf("decoded %+v", out)
+	}
+	if len(p.requests) != 2 || !strings.Contains(p.requests[1].Prompt, "above the maximum") {
+		t.Errorf("second request should repair the validation error, got %d requests", len(p.requests))
+	}
+	if p.requests[0].Schema == nil || p.requests[0].Schema.Name != "findings" {
+		t.Error("requests should carry the schema")
+	}
+	if resp.Usage.TotalTokens() != 4 {
+		t.Errorf("Usage = %d tokens, want both attempts counted", resp.Usage.TotalTokens())
+	}
+}
+
+func TestGenerateJSONGivesUp(t *testing.T) {
+	p := &scriptedProvider{answers: []string{"I cannot do that"}}
+
+	var out testFindings
+	_, err := GenerateJSON(context.Background(), p, "findings", "Review it", DefaultOptions(), &out)
+	if !errors.Is(err, ErrInvalidOutput) {
+		t.Errorf("error = %v, want ErrInvalidOutput", err)
+	}
+	if len(p.requests) != maxRepairs+1 {
+		t.Errorf("got %d requests, want %d", len(p.requests), maxRepairs+1)
+	}
+}
+
+func TestOpenAISendsResponseFormat(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"choices":[{"message":{"content":"{\"score\":1,\"status\":\"pass\",\"items\":[]}"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-4o")
+	p.baseURL = server.URL
+
+	var out testFindings
+	if _, err := GenerateJSON(context.Background(), p, "findings", "Review it", DefaultOptions(), &out); err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	format, _ := body["response_format"].(map[string]interface{})
+	if format["type"] != "json_schema" {
+		t.Errorf("response_format = %v, want json_schema", body["response_format"])
+	}
+}
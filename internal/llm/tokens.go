@@ -0,0 +1,68 @@
+package llm
+
+import "strings"
+
+// DefaultContextWindow is assumed for models whose context window is
+// unknown. It is deliberately small so unknown local models do not overflow.
+const DefaultContextWindow = 8192
+
+// contextWindows maps model name prefixes to context windows in tokens.
+// Longer prefixes are matched first.
+var contextWindows = map[string]int{
+	"gpt-4o":             128000,
+	"gpt-4-turbo":        128000,
+	"gpt-4.1":            1047576,
+	"gpt-4":              8192,
+	"gpt-3.5-turbo":      16385,
+	"o1":                 200000,
+	"o3":                 200000,
+	"o4":                 200000,
+	"claude-3":           200000,
+	"claude-sonnet":      200000,
+	"claude-opus":        200000,
+	"claude-haiku":       200000,
+	"anthropic/claude":   200000,
+	"openai/gpt-4o":      128000,
+	"meta-llama/llama-3": 131072,
+	"llama3":             8192,
+	"llama3.1":           131072,
+	"llama3.2":           131072,
+	"llama2":             4096,
+	"codellama":          16384,
+	"mistral":            32768,
+	"mixtral":            32768,
+	"qwen2.5":            32768,
+	"deepseek-coder":     16384,
+	"gemma2":             8192,
+	"phi3":               4096,
+}
+
+// ContextWindow returns the context window of model in tokens, or
+// DefaultContextWindow when the model is unknown
+func ContextWindow(model string) int {
+	if window, ok := knownContextWindow(model); ok {
+		return window
+	}
+	return DefaultContextWindow
+}
+
+// knownContextWindow looks model up in contextWindows
+func knownContextWindow(model string) (int, bool) {
+	model = strings.ToLower(model)
+	best := ""
+	for prefix := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return contextWindows[best], true
+}
+
+// EstimateTokens approximates the number of tokens in text. It assumes
+// about four characters per token, which slightly overestimates code.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,23 @@
+package llm
+
+import "testing"
+
+func TestContextWindow(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-4o-mini", 128000},
+		{"gpt-4", 8192},
+		{"claude-3-5-sonnet-20241022", 200000},
+		{"llama3.1:8b", 131072},
+		{"llama3:latest", 8192},
+		{"", DefaultContextWindow},
+		{"some-local-model", DefaultContextWindow},
+	}
+	for _, tt := range tests {
+		if got := ContextWindow(tt.model); got != tt.want {
+			t.Errorf("ContextWindow(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// maxToolRounds bounds how many rounds of tool calls SendWithTools runs
+// before giving up on a final answer
+const maxToolRounds = 10
+
+// ToolSpec describes a tool the model may call
+type ToolSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"` // JSON Schema of the arguments object
+}
+
+// ToolCall is a request by the model to run a tool. ID is empty for
+// providers that do not identify calls.
+type ToolCall struct {
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Tool is a Go function the model can call. Run receives the arguments as
+// JSON and returns the text sent back to the model.
+type Tool struct {
+	ToolSpec
+	Run func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+// NewTool creates a tool whose parameters are described by the struct
+// type of args, as for SchemaFor, and decodes them into a new value of
+// that type for run
+func NewTool[T any](name, description string, run func(ctx context.Context, args T) (string, error)) Tool {
+	var zero T
+	return Tool{
+		ToolSpec: ToolSpec{
+			Name:        name,
+			Description: description,
+			Parameters:  SchemaFor(name, zero).Schema,
+		},
+		Run: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args T
+			if len(raw) > 0 {
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return "", fmt.Errorf("invalid arguments: %w", err)
+				}
+			}
+			return run(ctx, args)
+		},
+	}
+}
+
+// ToolRegistry holds the tools offered to a model
+type ToolRegistry struct {
+	tools map[string]Tool
+	order []string
+}
+
+// NewToolRegistry creates a registry holding tools
+func NewToolRegistry(tools ...Tool) *ToolRegistry {
+	r := &ToolRegistry{tools: make(map[string]Tool)}
+	for _, t := range tools {
+		r.Register(t)
+	}
+	return r
+}
+
+// Register adds t, replacing any tool with the same name
+func (r *ToolRegistry) Register(t Tool) {
+	if _, ok := r.tools[t.Name]; !ok {
+		r.order = append(r.order, t.Name)
+	}
+	r.tools[t.Name] = t
+}
+
+// Specs returns the descriptions of the tools in registration order
+func (r *ToolRegistry) Specs() []ToolSpec {
+	specs := make([]ToolSpec, 0, len(r.order))
+	for _, name := range r.order {
+		specs = append(specs, r.tools[name].ToolSpec)
+	}
+	return specs
+}
+
+// Call runs the tool named by call. Failures are returned as text so the
+// model can correct itself.
+func (r *ToolRegistry) Call(ctx context.Context, call ToolCall) string {
+	t, ok := r.tools[call.Name]
+	if !ok {
+		return fmt.Sprintf("error: unknown tool %q", call.Name)
+	}
+	out, err := t.Run(ctx, call.Arguments)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return out
+}
+
+// SendWithTools appends text as a user message and sends the conversation
+// to p with the tools of r. Every tool the model calls is run and its
+// result sent back until the model answers in text, which is appended as
+// the reply. The returned response carries the usage and cost of every
+// round. On error the conversation is left unchanged.
+func (c *Conversation) SendWithTools(ctx context.Context, p Provider, text string, r *ToolRegistry, opts Options) (*GenerateResponse, error) {
+	start := len(c.Messages)
+	c.AddUser(text)
+
+	var total GenerateResponse
+	for round := 0; round < maxToolRounds; round++ {
+		req := c.Request(opts)
+		req.Tools = r.Specs()
+		resp, err := p.Generate(ctx, req)
+		if err != nil {
+			c.Messages = c.Messages[:start]
+			return nil, err
+		}
+		usage, cost := total.Usage.Add(resp.Usage), total.Cost+resp.Cost
+		total = *resp
+		total.Usage, total.Cost = usage, cost
+
+		if len(resp.ToolCalls) == 0 {
+			c.AddAssistant(resp.Text)
+			return &total, nil
+		}
+		c.Messages = append(c.Messages, Message{Role: RoleAssistant, Content: resp.Text, ToolCalls: resp.ToolCalls})
+		for _, call := range resp.ToolCalls {
+			c.Messages = append(c.Messages, Message{
+				Role:       RoleTool,
+				Content:    r.Call(ctx, call),
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+			})
+		}
+	}
+	c.Messages = c.Messages[:start]
+	return nil, fmt.Errorf("%s was still calling tools after %d rounds", p.Name(), maxToolRounds)
+}
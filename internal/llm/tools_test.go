@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type echoArgs struct {
+	Text string `json:"text"`
+}
+
+func echoTool() Tool {
+	return NewTool("echo", "Repeat text", func(ctx context.Context, args echoArgs) (string, error) {
+		return "echo: " + args.Text, nil
+	})
+}
+
+// toolCallingProvider calls the echo tool once, then answers with the
+// result it was sent
+type toolCallingProvider struct {
+	MockProvider
+	requests []GenerateRequest
+}
+
+func (p *toolCallingProvider) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	p.requests = append(p.requests, req)
+	usage := Usage{PromptTokens: 10, CompletionTokens: 2}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != RoleTool {
+		return &GenerateResponse{
+			FinishReason: FinishToolCalls,
+			Usage:        usage,
+			ToolCalls:    []ToolCall{{ID: "call_1", Name: "echo", Arguments: json.RawMessage(`{"text":"hi"}`)}},
+		}, nil
+	}
+	return &GenerateResponse{Text: "The tool said " + last.Content, FinishReason: FinishStop, Usage: usage}, nil
+}
+
+func TestSendWithTools(t *testing.T) {
+	p := &toolCallingProvider{}
+	conv := NewConversation("")
+
+	resp, err := conv.SendWithTools(context.Background(), p, "Say hi", NewToolRegistry(echoTool()), DefaultOptions())
+	if err != nil {
+		t.Fatalf("SendWithTools failed: %v", err)
+	}
+	if resp.Text != "The tool said echo: hi" {
+		t.Errorf("Text = %q", resp.Text)
+	}
+	if resp.Usage.TotalTokens() != 24 {
+		t.Errorf("Usage = %d tokens, want both rounds counted", resp.Usage.TotalTokens())
+	}
+	if len(p.requests[0].Tools) != 1 || p.requests[0].Tools[0].Name != "echo" {
+		t.Errorf("requests should offer the echo tool, got %+v", p.requests[0].Tools)
+	}
+
+	roles := []string{}
+	for _, m := range conv.Messages {
+		roles = append(roles, m.Role)
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,tool,assistant" {
+		t.Errorf("conversation roles = %s", got)
+	}
+	if call := conv.Messages[2]; call.ToolCallID != "call_1" || call.ToolName != "echo" {
+		t.Errorf("tool result = %+v, want it linked to call_1", call)
+	}
+}
+
+func TestToolRegistryCall(t *testing.T) {
+	r := NewToolRegistry(echoTool())
+
+	if got := r.Call(context.Background(), ToolCall{Name: "missing"}); !strings.HasPrefix(got, "error:") {
+		t.Errorf("unknown tool: got %q, want an error for the model", got)
+	}
+	if got := r.Call(context.Background(), ToolCall{Name: "echo", Arguments: json.RawMessage(`{"text":`)}); !strings.HasPrefix(got, "error:") {
+		t.Errorf("bad arguments: got %q, want an error for the model", got)
+	}
+	params := r.Specs()[0].Parameters
+	if params["type"] != "object" || params["properties"].(map[string]interface{})["text"] == nil {
+		t.Errorf("Parameters = %v, want the schema of echoArgs", params)
+	}
+}
+
+func TestProvidersMapToolCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		provider func(url string) Provider
+		check    func(t *testing.T, body map[string]interface{})
+	}{
+		{
+			name:     "openai",
+			response: `{"choices":[{"message":{"content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"echo","arguments":"{\"text\":\"hi\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			provider: func(url string) Provider {
+				p := NewOpenAIProvider("key", "gpt-4o")
+				p.baseURL = url
+				return p
+			},
+			check: func(t *testing.T, body map[string]interface{}) {
+				tool := body["tools"].([]interface{})[0].(map[string]interface{})
+				if tool["type"] != "function" || tool["function"].(map[string]interface{})["name"] != "echo" {
+					t.Errorf("tools = %v", body["tools"])
+				}
+				messages := body["messages"].([]interface{})
+				result := messages[len(messages)-1].(map[string]interface{})
+				if result["role"] != "tool" || result["tool_call_id"] != "call_0" {
+					t.Errorf("tool result = %v", result)
+				}
+				call := messages[len(messages)-2].(map[string]interface{})["tool_calls"].([]interface{})[0].(map[string]interface{})
+				if call["function"].(map[string]interface{})["arguments"] != `{"text":"earlier"}` {
+					t.Errorf("tool call = %v, want arguments as a JSON string", call)
+				}
+			},
+		},
+		{
+			name:     "anthropic",
+			response: `{"content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"call_1","name":"echo","input":{"text":"hi"}}],"stop_reason":"tool_use"}`,
+			provider: func(url string) Provider {
+				p := NewAnthropicProvider("key", "")
+				p.baseURL = url
+				return p
+			},
+			check: func(t *testing.T, body map[string]interface{}) {
+				tool := body["tools"].([]interface{})[0].(map[string]interface{})
+				if tool["name"] != "echo" || tool["input_schema"] == nil {
+					t.Errorf("tools = %v", body["tools"])
+				}
+				messages := body["messages"].([]interface{})
+				result := messages[len(messages)-1].(map[string]interface{})
+				block := result["content"].([]interface{})[0].(map[string]interface{})
+				if result["role"] != "user" || block["type"] != "tool_result" || block["tool_use_id"] != "call_0" {
+					t.Errorf("tool result = %v", result)
+				}
+				call := messages[len(messages)-2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+				if call["type"] != "tool_use" || call["input"].(map[string]interface{})["text"] != "earlier" {
+					t.Errorf("tool call = %v, want a tool_use block", call)
+				}
+			},
+		},
+		{
+			name:     "ollama",
+			response: `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"echo","arguments":{"text":"hi"}}}]},"done":true,"done_reason":"stop"}`,
+			provider: func(url string) Provider {
+				return NewOllamaProvider(url, "llama3.1")
+			},
+			check: func(t *testing.T, body map[string]interface{}) {
+				if body["tools"] == nil {
+					t.Error("request should offer the tools")
+				}
+				messages := body["messages"].([]interface{})
+				result := messages[len(messages)-1].(map[string]interface{})
+				if result["role"] != "tool" || result["tool_name"] != "echo" {
+					t.Errorf("tool result = %v", result)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&body)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			conv := NewConversation("")
+			conv.AddUser("Say hi")
+			conv.Messages = append(conv.Messages,
+				Message{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_0", Name: "echo", Arguments: json.RawMessage(`{"text":"earlier"}`)}}},
+				Message{Role: RoleTool, Content: "echo: earlier", ToolCallID: "call_0", ToolName: "echo"},
+			)
+			req := conv.Request(DefaultOptions())
+			req.Tools = NewToolRegistry(echoTool()).Specs()
+
+			resp, err := tt.provider(server.URL).Generate(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Generate failed: %v", err)
+			}
+			if resp.FinishReason != FinishToolCalls || len(resp.ToolCalls) != 1 {
+				t.Fatalf("FinishReason = %q with %d tool calls", resp.FinishReason, len(resp.ToolCalls))
+			}
+			var args echoArgs
+			if call := resp.ToolCalls[0]; call.Name != "echo" || json.Unmarshal(call.Arguments, &args) != nil || args.Text != "hi" {
+				t.Errorf("ToolCalls[0] = %+v", resp.ToolCalls[0])
+			}
+			tt.check(t, body)
+		})
+	}
+}
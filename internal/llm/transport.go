@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how transient provider failures are retried
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first; 1 disables retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled on each further retry
+	MaxDelay    time.Duration // Upper bound on the backoff delay
+}
+
+// DefaultRetryPolicy returns sensible defaults
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// backoff returns the delay before retry number attempt (starting at 1):
+// exponential in the attempt with jitter in [d/2, d]
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// delay returns how long to wait after err before retry number attempt. A
+// server-requested Retry-After takes precedence over the backoff, up to
+// MaxDelay.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if p.MaxDelay > 0 {
+			return min(apiErr.RetryAfter, p.MaxDelay)
+		}
+		return apiErr.RetryAfter
+	}
+	return p.backoff(attempt)
+}
+
+// retryable reports whether err is worth another attempt
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	// Network errors; cancellation is checked by the caller
+	return true
+}
+
+// doRequest sends req, retrying rate limits, overloads, server errors and
+// network failures under policy. It gives up early when the wait before a
+// retry would outlast the request's deadline. It returns the response on
+// HTTP 200 and an *APIError for any other status.
+func doRequest(client *http.Client, req *http.Request, provider string, policy RetryPolicy) (*http.Response, error) {
+	ctx := req.Context()
+	attempts := max(policy.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			wait := policy.delay(attempt-1, lastErr)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				return nil, lastErr
+			}
+			if err := sleepContext(ctx, wait); err != nil {
+				return nil, lastErr
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req.Body = body
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%s request failed: %w", provider, err)
+			}
+			lastErr = fmt.Errorf("%s request failed: %w", provider, err)
+			continue
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+
+		apiErr := newAPIError(provider, resp)
+		resp.Body.Close()
+		lastErr = apiErr
+		if !retryable(apiErr) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
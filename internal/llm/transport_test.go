@@ -0,0 +1,145 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fastRetry retries quickly so tests do not sleep
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func TestRetryOnRateLimit(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"type":"rate_limit_error","message":"slow down"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"model":"llama3","response":"ok","done":true}`)
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(server.URL, "llama3")
+	p.SetRetryPolicy(fastRetry)
+
+	resp, err := p.Generate(context.Background(), GenerateRequest{Prompt: "hi"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Text != "ok" || calls != 3 {
+		t.Errorf("Expected success on the third attempt, got %q after %d calls", resp.Text, calls)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantKind  error
+		wantCalls int
+	}{
+		{"overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrOverloaded, 3},
+		{"quota", http.StatusTooManyRequests, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`, ErrQuotaExceeded, 1},
+		{"auth", http.StatusUnauthorized, `{"error":{"message":"Incorrect API key","code":"invalid_api_key"}}`, ErrAuth, 1},
+		{"context length", http.StatusBadRequest, `{"error":{"message":"This model's maximum context length is 8192 tokens","code":"context_length_exceeded"}}`, ErrContextLength, 1},
+		{"prompt too long", http.StatusBadRequest, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 250000 tokens > 200000 maximum"}}`, ErrContextLength, 1},
+		{"bad request", http.StatusBadRequest, `{"error":"invalid model"}`, ErrProviderFailed, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			p := NewOpenAIProvider("sk-test", "gpt-4")
+			p.baseURL = server.URL
+			p.SetRetryPolicy(fastRetry)
+
+			_, err := p.Generate(context.Background(), GenerateRequest{Prompt: "hi"})
+			if !errors.Is(err, tt.wantKind) {
+				t.Errorf("error = %v, want %v", err, tt.wantKind)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("expected *APIError with status %d, got %#v", tt.status, err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(server.URL, "llama3")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.Generate(ctx, GenerateRequest{Prompt: "hi"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("error = %v, want ErrRateLimited", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("a Retry-After wait past the deadline should give up at once")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"7":                             7 * time.Second,
+		"-1":                            0,
+		"Mon, 01 Jan 2024 12:00:30 GMT": 30 * time.Second,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, want := range tests {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt <= 8; attempt++ {
+		ceiling := min(policy.BaseDelay<<(attempt-1), policy.MaxDelay)
+		for i := 0; i < 20; i++ {
+			d := policy.backoff(attempt)
+			if d < ceiling/2 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+
+	err := &APIError{Kind: ErrRateLimited, RetryAfter: 300 * time.Millisecond}
+	if d := policy.delay(1, err); d != 300*time.Millisecond {
+		t.Errorf("delay() = %v, want Retry-After of 300ms", d)
+	}
+	err.RetryAfter = time.Hour
+	if d := policy.delay(1, err); d != policy.MaxDelay {
+		t.Errorf("delay() = %v, want Retry-After capped at MaxDelay", d)
+	}
+}
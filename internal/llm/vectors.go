@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// VectorStore is a file of embedded text for similarity search. All
+// vectors come from one model; changing the model starts afresh.
+type VectorStore struct {
+	path string
+
+	Model      string        `json:"model"`
+	Dimensions int           `json:"dimensions"`
+	Entries    []VectorEntry `json:"entries"`
+}
+
+// VectorEntry is a piece of text and its embedding
+type VectorEntry struct {
+	ID     string    `json:"id"`     // Names the text, e.g. a file and line range
+	Source string    `json:"source"` // File or document the text came from
+	Hash   string    `json:"hash"`   // Hash of Text, to reuse the vector while the text is unchanged
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// VectorMatch is an entry found by Search
+type VectorMatch struct {
+	VectorEntry
+	Score float64 // Cosine similarity to the query, from -1 to 1
+}
+
+// OpenVectorStore reads the store at path, or returns an empty store when
+// the file does not exist yet
+func OpenVectorStore(path string) (*VectorStore, error) {
+	s := &VectorStore{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid vector store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Path returns the file the store is saved to
+func (s *VectorStore) Path() string {
+	return s.path
+}
+
+// Save writes the store to its file
+func (s *VectorStore) Save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// Vectors returns the stored vectors by text hash, or nil when they were
+// not embedded by model
+func (s *VectorStore) Vectors(model string) map[string][]float32 {
+	if model != s.Model {
+		return nil
+	}
+	vectors := make(map[string][]float32, len(s.Entries))
+	for _, e := range s.Entries {
+		vectors[e.Hash] = e.Vector
+	}
+	return vectors
+}
+
+// Replace sets the contents of the store to entries embedded by model
+func (s *VectorStore) Replace(model string, entries []VectorEntry) error {
+	vectors := make([][]float32, len(entries))
+	for i, e := range entries {
+		vectors[i] = e.Vector
+	}
+	dims, err := dimensions(vectors)
+	if err != nil {
+		return err
+	}
+	s.Model, s.Dimensions, s.Entries = model, dims, entries
+	return nil
+}
+
+// Search returns the k entries most similar to query, best first. k of
+// zero or less returns every entry.
+func (s *VectorStore) Search(query []float32, k int) ([]VectorMatch, error) {
+	if len(s.Entries) == 0 {
+		return nil, nil
+	}
+	if len(query) != s.Dimensions {
+		return nil, fmt.Errorf("query has %d dimensions, the store holds vectors of %d", len(query), s.Dimensions)
+	}
+
+	matches := make([]VectorMatch, len(s.Entries))
+	for i, e := range s.Entries {
+		matches[i] = VectorMatch{VectorEntry: e, Score: cosine(query, e.Vector)}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if k > 0 && k < len(matches) {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// cosine returns the cosine similarity of a and b, which have equal length
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -5,35 +5,42 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
 )
 
 // ChangeItem represents a detected change
 type ChangeItem struct {
-	ID          string
-	Description string
-	SpecSection string
-	CodePath    string
-	Status      string // "pending", "approved", "rejected", "deferred"
-	Reason      string
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	SpecSection string `json:"spec_section"`
+	CodePath    string `json:"code_path"`
+	Status      string `json:"status"` // "pending", "approved", "rejected", "deferred"
+	Reason      string `json:"reason,omitempty"`
 }
 
 // ChangeOrderResult holds the change order analysis
 type ChangeOrderResult struct {
-	SpecFile     string
-	CodebasePath string
-	Changes      []ChangeItem
-	FullReport   string
+	SpecFile     string       `json:"spec_file"`
+	CodebasePath string       `json:"codebase_path"`
+	Changes      []ChangeItem `json:"changes"`
+	FullReport   string       `json:"full_report"`
+	GeneratedBy  Generation   `json:"generated_by"`
 }
 
 // ChangeOrderMode handles the CHANGE_ORDER workflow
 type ChangeOrderMode struct {
-	provider     llm.Provider
-	contractsDir string
-	result       ChangeOrderResult
+	provider      llm.Provider
+	prompts       *prompts.Set
+	settings      Settings
+	stream        StreamHandler
+	contextWindow int
+	contractsDir  string
+	result        ChangeOrderResult
 }
 
 // NewChangeOrderMode creates a new change order mode
@@ -43,6 +50,7 @@ func NewChangeOrderMode(provider llm.Provider, contractsDir string) *ChangeOrder
 	}
 	return &ChangeOrderMode{
 		provider:     provider,
+		prompts:      prompts.Default(),
 		contractsDir: contractsDir,
 	}
 }
@@ -57,68 +65,160 @@ func (m *ChangeOrderMode) SetCodebasePath(path string) {
 	m.result.CodebasePath = path
 }
 
+// SetPrompts sets the prompt templates, which default to the built-in ones
+func (m *ChangeOrderMode) SetPrompts(set *prompts.Set) {
+	m.prompts = set
+}
+
+// SetSettings tunes the model and parameters of the mode's LLM requests,
+// and sets its prompt templates and context window when s carries them
+func (m *ChangeOrderMode) SetSettings(s Settings) {
+	m.settings = s
+	if s.Prompts != nil {
+		m.prompts = s.Prompts
+	}
+	if s.ContextWindow > 0 {
+		m.contextWindow = s.ContextWindow
+	}
+}
+
+// SetStreamHandler streams generated text to fn while the LLM responds
+func (m *ChangeOrderMode) SetStreamHandler(fn StreamHandler) {
+	m.stream = fn
+}
+
+// SetContextWindow sets the context window of the model in tokens. Zero
+// uses the window of the requested model.
+func (m *ChangeOrderMode) SetContextWindow(tokens int) {
+	m.contextWindow = tokens
+}
+
 // DetectDrift analyzes spec vs code for intentional drift
 func (m *ChangeOrderMode) DetectDrift(ctx context.Context) (*ChangeOrderResult, error) {
+	ctx, cancel := m.settings.withTimeout(ctx)
+	defer cancel()
+
 	specContent, err := os.ReadFile(m.result.SpecFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read spec: %w", err)
 	}
 
-	var codeContent strings.Builder
-	filepath.Walk(m.result.CodebasePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		if strings.Contains(path, "/.git/") || strings.Contains(path, "/vendor/") {
-			return nil
-		}
-		if isCodeFile(path) {
-			data, err := os.ReadFile(path)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "warning: failed to read file %s: %v\n", path, err)
-				return nil
-			}
-			if len(data) < 10000 {
-				codeContent.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", path, string(data)))
-			}
-		}
-		return nil
-	})
+	files, err := collectSources(m.result.CodebasePath, isCodeFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read codebase: %w", err)
+	}
 
 	if m.provider == nil {
+		m.result.GeneratedBy = templateGeneration(nil)
 		return m.generateTemplateChangeOrder(string(specContent)), nil
 	}
 
-	prompt := fmt.Sprintf(`Compare this specification with the codebase and identify intentional drift (changes that deviate from spec).
-
-SPECIFICATION:
-%s
-
-CODEBASE:
-%s
-
-List each deviation as:
-- ID: CO-XXX
-- Description: What changed
-- Spec Section: Which part of spec it affects
-- Code Path: Where in code
-
-Format as Markdown.`, string(specContent), codeContent.String())
+	spec := string(specContent)
+	drift := analysis{
+		prompts: m.prompts,
+		spec:    spec,
+		single:  "change_order",
+		batch:   "change_order_batch",
+		reduce:  "change_order_reduce",
+	}
 
+	system, err := m.prompts.Render("change_order_system", nil)
+	if err != nil {
+		return nil, err
+	}
 	opts := llm.DefaultOptions()
-	opts.SystemPrompt = "You are analyzing code drift from specifications."
+	opts.SystemPrompt = system
 	opts.MaxTokens = 4096
+	opts = m.settings.options(opts)
 
-	report, err := m.provider.Complete(ctx, prompt, opts)
+	conv, gen, err := analyze(ctx, m.provider, m.stream, newPlanner(m.contextWindow, opts.Model), drift, files, opts)
+	if aborted(err) {
+		return nil, err
+	}
 	if err != nil {
+		m.result.GeneratedBy = templateGeneration(err)
 		return m.generateTemplateChangeOrder(string(specContent)), nil
 	}
 
-	m.result.FullReport = report
-	m.parseChanges(report)
+	report := conv.LastReply()
+	if err := m.extractChanges(ctx, report, &gen); err != nil {
+		return nil, err
+	}
+	m.result.GeneratedBy = gen
+	m.result.FullReport = report + gen.Footer()
 	return &m.result, nil
 }
 
+// driftFindings is the structured form of a drift report
+type driftFindings struct {
+	Changes []struct {
+		ID          string `json:"id" desc:"Identifier in the form CO-001"`
+		Description string `json:"description" desc:"What changed"`
+		SpecSection string `json:"spec_section" desc:"Which part of the specification it affects"`
+		CodePath    string `json:"code_path" desc:"Where in the code"`
+	} `json:"changes"`
+}
+
+// extractChanges fills the result from report with structured output,
+// falling back to parseChanges when no valid changes are produced. It
+// fails only when the run must stop, as when the budget is exhausted.
+func (m *ChangeOrderMode) extractChanges(ctx context.Context, report string, gen *Generation) error {
+	var findings driftFindings
+	spent, err := extract(ctx, m.provider, m.settings.Model, m.prompts, "change_order_extract", "drift", report, &findings)
+	gen.addUsage(spent)
+	if aborted(err) {
+		return err
+	}
+	if err != nil {
+		m.parseChanges(report)
+		return nil
+	}
+
+	changes := make([]ChangeItem, len(findings.Changes))
+	for i, c := range findings.Changes {
+		changes[i] = ChangeItem{
+			ID:          c.ID,
+			Description: c.Description,
+			SpecSection: c.SpecSection,
+			CodePath:    c.CodePath,
+			Status:      "pending",
+		}
+	}
+	m.result.Changes = numberChanges(changes)
+	return nil
+}
+
+var changeIDPattern = regexp.MustCompile(`^CO-\d{3,}$`)
+
+// numberChanges makes the ID of every change a unique CO-NNN, keeping the
+// valid IDs the model gave and numbering the rest with the lowest unused
+// numbers
+func numberChanges(changes []ChangeItem) []ChangeItem {
+	used := map[string]bool{}
+	valid := make([]bool, len(changes))
+	for i := range changes {
+		id := strings.ToUpper(strings.TrimSpace(changes[i].ID))
+		if changeIDPattern.MatchString(id) && !used[id] {
+			changes[i].ID = id
+			used[id] = true
+			valid[i] = true
+		}
+	}
+
+	next := 1
+	for i := range changes {
+		if valid[i] {
+			continue
+		}
+		for used[fmt.Sprintf("CO-%03d", next)] {
+			next++
+		}
+		changes[i].ID = fmt.Sprintf("CO-%03d", next)
+		used[changes[i].ID] = true
+	}
+	return changes
+}
+
 func (m *ChangeOrderMode) generateTemplateChangeOrder(spec string) *ChangeOrderResult {
 	m.result.Changes = []ChangeItem{
 		{
@@ -143,16 +243,41 @@ func (m *ChangeOrderMode) generateTemplateChangeOrder(spec string) *ChangeOrderR
 		sb.WriteString(fmt.Sprintf("- **Status:** %s\n\n", c.Status))
 	}
 	sb.WriteString("\n*Configure LLM for detailed drift analysis*\n")
+	sb.WriteString(m.result.GeneratedBy.Footer())
 
 	m.result.FullReport = sb.String()
 	return &m.result
 }
 
+// changeFieldPattern matches the "- ID: CO-001" style lines the
+// change_order_instructions template asks for, with optional bold markup
+var changeFieldPattern = regexp.MustCompile(`(?im)^\s*[-*]?\s*\**(ID|Description|Spec Section|Code Path)\**\s*:\s*\**\s*(.*?)\s*$`)
+
+// parseChanges reads the changes from a free-text report when structured
+// extraction fails. Each "ID:" line starts a change; a report without
+// them yields none.
 func (m *ChangeOrderMode) parseChanges(report string) {
-	// Simple parsing - would be more sophisticated in production
-	m.result.Changes = []ChangeItem{
-		{ID: "CO-001", Description: "See full report", Status: "pending"},
+	var changes []ChangeItem
+	for _, match := range changeFieldPattern.FindAllStringSubmatch(report, -1) {
+		field, value := strings.ToLower(match[1]), match[2]
+		if field == "id" {
+			changes = append(changes, ChangeItem{ID: value, Status: "pending"})
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		c := &changes[len(changes)-1]
+		switch field {
+		case "description":
+			c.Description = value
+		case "spec section":
+			c.SpecSection = value
+		case "code path":
+			c.CodePath = value
+		}
 	}
+	m.result.Changes = numberChanges(changes)
 }
 
 // ApproveChange approves a change
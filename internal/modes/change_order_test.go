@@ -1,6 +1,8 @@
 package modes
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 )
 
@@ -41,3 +43,77 @@ func TestChangeTracking(t *testing.T) {
 		})
 	}
 }
+
+func TestDetectDriftStructuredChanges(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+
+	provider := &recordingProvider{json: `{"changes": [
+		{"id": "CO-001", "description": "Sessions use JWT", "spec_section": "Auth", "code_path": "auth.go"},
+		{"id": "", "description": "Rate limit raised", "spec_section": "API", "code_path": "api.go"}
+	]}`}
+	co := NewChangeOrderMode(provider, dir)
+	co.SetSpecFile(spec)
+	co.SetCodebasePath(dir)
+
+	result, err := co.DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift failed: %v", err)
+	}
+	if len(result.Changes) != 2 {
+		t.Fatalf("got %d changes, want 2", len(result.Changes))
+	}
+	if c := result.Changes[1]; c.ID != "CO-002" || c.CodePath != "api.go" || c.Status != "pending" {
+		t.Errorf("second change = %+v", c)
+	}
+}
+
+func TestParseChanges(t *testing.T) {
+	report := `## Detected Changes
+
+- **ID:** CO-001
+- **Description:** Sessions use JWT
+- **Spec Section:** Auth
+- **Code Path:** auth.go
+
+- ID: CO-001
+- Description: Rate limit raised
+- Spec Section: API
+- Code Path: api.go
+`
+	m := NewChangeOrderMode(nil, "")
+	m.parseChanges(report)
+	changes := m.result.Changes
+	if len(changes) != 2 {
+		t.Fatalf("got %d changes, want 2: %+v", len(changes), changes)
+	}
+	if c := changes[0]; c.ID != "CO-001" || c.Description != "Sessions use JWT" || c.SpecSection != "Auth" || c.CodePath != "auth.go" {
+		t.Errorf("first change = %+v", c)
+	}
+	if c := changes[1]; c.ID != "CO-002" || c.CodePath != "api.go" || c.Status != "pending" {
+		t.Errorf("second change = %+v, want the repeated ID renumbered", c)
+	}
+
+	m.parseChanges("The code matches the specification.")
+	if len(m.result.Changes) != 0 {
+		t.Errorf("a report without changes should yield none, got %+v", m.result.Changes)
+	}
+}
+
+func TestNumberChanges(t *testing.T) {
+	changes := numberChanges([]ChangeItem{
+		{ID: "bogus"},
+		{ID: "co-001"},
+		{ID: "CO-001"},
+		{ID: "CO-003"},
+		{},
+	})
+	want := []string{"CO-002", "CO-001", "CO-004", "CO-003", "CO-005"}
+	for i, c := range changes {
+		if c.ID != want[i] {
+			t.Errorf("changes[%d].ID = %q, want %q", i, c.ID, want[i])
+		}
+	}
+}
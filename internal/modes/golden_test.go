@@ -0,0 +1,156 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+)
+
+// The golden tests run each mode's LLM path against responses recorded in
+// testdata/cassettes and compare the output with testdata/golden. After
+// changing a prompt, re-record with a real provider and review the diff:
+//
+//	OPENAI_API_KEY=... go test ./internal/modes -run Golden -record openai -update
+var (
+	record = flag.String("record", "", "re-record cassettes against this provider: ollama, openai or anthropic")
+	update = flag.Bool("update", false, "rewrite golden files with the current output")
+)
+
+const (
+	goldenSpec    = "testdata/spec.md"
+	goldenProject = "testdata/project"
+)
+
+// cassetteProvider replays the named cassette, or records it from the
+// provider named by -record
+func cassetteProvider(t *testing.T, name string) llm.Provider {
+	t.Helper()
+	path := filepath.Join("testdata", "cassettes", name+".json")
+	if *record == "" {
+		p, err := llm.NewReplayProvider(path)
+		if err != nil {
+			t.Fatalf("loading cassette: %v", err)
+		}
+		return p
+	}
+
+	p, err := llm.NewProvider(llm.Config{
+		Type:    llm.ProviderType(*record),
+		APIKey:  os.Getenv(strings.ToUpper(*record) + "_API_KEY"),
+		BaseURL: os.Getenv("OLLAMA_HOST"),
+		Model:   os.Getenv("FACTORY_RECORD_MODEL"),
+	})
+	if err != nil {
+		t.Fatalf("creating %s provider: %v", *record, err)
+	}
+	recorder := llm.NewRecordingProvider(p)
+	t.Cleanup(func() {
+		if err := recorder.Save(path); err != nil {
+			t.Errorf("saving cassette: %v", err)
+		}
+	})
+	return recorder
+}
+
+// checkGolden compares got with the named golden file, or rewrites it
+// with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("writing golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s; rerun with -update and review the diff\ngot:\n%s", path, got)
+	}
+}
+
+func checkGoldenJSON(t *testing.T, name string, v interface{}) {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, name, string(data)+"\n")
+}
+
+func TestIntakeGolden(t *testing.T) {
+	intake := NewIntakeMode(cassetteProvider(t, "intake"), t.TempDir())
+	intake.SetData(IntakeData{
+		ProjectName:          "Notes Service",
+		Description:          "A small HTTP service for storing and retrieving notes.",
+		TargetUsers:          "Developers who need a scratch pad for their tools",
+		CoreFeatures:         "Create notes\nFetch a note by ID\nRequest logging",
+		TechnicalConstraints: "Go standard library only, in-memory storage",
+		SuccessCriteria:      "p99 latency under 10ms",
+	})
+
+	spec, err := intake.GenerateSpec(context.Background())
+	if err != nil {
+		t.Fatalf("GenerateSpec failed: %v", err)
+	}
+	if intake.GeneratedBy().Templated() {
+		t.Fatalf("fell back to the template: %s", intake.GeneratedBy().Error)
+	}
+	checkGolden(t, "intake_spec.md", spec)
+}
+
+func TestReviewGolden(t *testing.T) {
+	review := NewReviewMode(cassetteProvider(t, "review"), t.TempDir())
+	review.SetSpecFile(goldenSpec)
+	review.SetCodePaths([]string{goldenProject})
+
+	result, err := review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if result.GeneratedBy.Templated() {
+		t.Fatalf("fell back to the template: %s", result.GeneratedBy.Error)
+	}
+	checkGoldenJSON(t, "review.json", result)
+}
+
+func TestRescueGolden(t *testing.T) {
+	rescue := NewRescueMode(cassetteProvider(t, "rescue"), t.TempDir(), t.TempDir())
+	rescue.SetCodebasePath(goldenProject)
+
+	result, err := rescue.ScanCodebase(context.Background())
+	if err != nil {
+		t.Fatalf("ScanCodebase failed: %v", err)
+	}
+	if result.GeneratedBy.Templated() {
+		t.Fatalf("fell back to the template: %s", result.GeneratedBy.Error)
+	}
+	checkGoldenJSON(t, "rescue.json", result)
+}
+
+func TestChangeOrderGolden(t *testing.T) {
+	co := NewChangeOrderMode(cassetteProvider(t, "change_order"), t.TempDir())
+	co.SetSpecFile(goldenSpec)
+	co.SetCodebasePath(goldenProject)
+
+	result, err := co.DetectDrift(context.Background())
+	if err != nil {
+		t.Fatalf("DetectDrift failed: %v", err)
+	}
+	if result.GeneratedBy.Templated() {
+		t.Fatalf("fell back to the template: %s", result.GeneratedBy.Error)
+	}
+	checkGoldenJSON(t, "change_order.json", result)
+}
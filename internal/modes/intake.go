@@ -2,13 +2,17 @@ package modes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/pelletier/go-toml/v2"
 	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
+	"gopkg.in/yaml.v3"
 )
 
 // IntakeStep represents a step in the intake interview
@@ -27,21 +31,62 @@ const (
 
 // IntakeData holds all collected information
 type IntakeData struct {
-	ProjectName          string
-	Description          string
-	TargetUsers          string
-	CoreFeatures         string
-	TechnicalConstraints string
-	SuccessCriteria      string
-	GeneratedSpec        string
+	ProjectName          string `json:"project_name" toml:"project_name" yaml:"project_name"`
+	Description          string `json:"description" toml:"description" yaml:"description"`
+	TargetUsers          string `json:"target_users" toml:"target_users" yaml:"target_users"`
+	CoreFeatures         string `json:"core_features" toml:"core_features" yaml:"core_features"`
+	TechnicalConstraints string `json:"technical_constraints" toml:"technical_constraints" yaml:"technical_constraints"`
+	SuccessCriteria      string `json:"success_criteria" toml:"success_criteria" yaml:"success_criteria"`
+	GeneratedSpec        string `json:"-" toml:"-" yaml:"-"`
+}
+
+// LoadIntakeData reads intake answers from a TOML, YAML or JSON file,
+// chosen by extension. Unknown fields are rejected.
+func LoadIntakeData(path string) (IntakeData, error) {
+	var data IntakeData
+
+	f, err := os.Open(path)
+	if err != nil {
+		return data, fmt.Errorf("failed to open answers: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		dec := toml.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&data)
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		err = dec.Decode(&data)
+	case ".json":
+		dec := json.NewDecoder(f)
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&data)
+	default:
+		return data, fmt.Errorf("unsupported answers format %q (want .toml, .yaml or .json)", filepath.Ext(path))
+	}
+	if err != nil {
+		return data, fmt.Errorf("failed to parse answers %s: %w", path, err)
+	}
+
+	if strings.TrimSpace(data.ProjectName) == "" {
+		return data, fmt.Errorf("answers %s: project_name is required", path)
+	}
+	return data, nil
 }
 
 // IntakeMode handles the INTAKE workflow
 type IntakeMode struct {
-	provider    llm.Provider
-	data        IntakeData
-	currentStep IntakeStep
+	provider     llm.Provider
+	prompts      *prompts.Set
+	settings     Settings
+	stream       StreamHandler
+	data         IntakeData
+	currentStep  IntakeStep
 	contractsDir string
+	generatedBy  Generation
 }
 
 // NewIntakeMode creates a new intake mode
@@ -51,6 +96,7 @@ func NewIntakeMode(provider llm.Provider, contractsDir string) *IntakeMode {
 	}
 	return &IntakeMode{
 		provider:     provider,
+		prompts:      prompts.Default(),
 		contractsDir: contractsDir,
 	}
 }
@@ -130,6 +176,32 @@ func (m *IntakeMode) GetStepValue(step IntakeStep) string {
 	}
 }
 
+// SetData sets all answers at once and moves to the preview step
+func (m *IntakeMode) SetData(data IntakeData) {
+	data.GeneratedSpec = ""
+	m.data = data
+	m.currentStep = StepPreview
+}
+
+// SetPrompts sets the prompt templates, which default to the built-in ones
+func (m *IntakeMode) SetPrompts(set *prompts.Set) {
+	m.prompts = set
+}
+
+// SetSettings tunes the model and parameters of the mode's LLM requests,
+// and sets its prompt templates when s carries them
+func (m *IntakeMode) SetSettings(s Settings) {
+	m.settings = s
+	if s.Prompts != nil {
+		m.prompts = s.Prompts
+	}
+}
+
+// SetStreamHandler streams generated text to fn while the LLM responds
+func (m *IntakeMode) SetStreamHandler(fn StreamHandler) {
+	m.stream = fn
+}
+
 // NextStep advances to the next step
 func (m *IntakeMode) NextStep() {
 	if m.currentStep < StepComplete {
@@ -148,51 +220,40 @@ func (m *IntakeMode) PrevStep() {
 func (m *IntakeMode) GenerateSpec(ctx context.Context) (string, error) {
 	if m.provider == nil {
 		// Fallback to template-based generation
+		m.generatedBy = templateGeneration(nil)
 		return m.generateTemplateSpec(), nil
 	}
 
-	prompt := fmt.Sprintf(`Based on the following project information, generate a comprehensive software specification document in Markdown format.
-
-Project Name: %s
-Description: %s
-Target Users: %s
-Core Features:
-%s
-Technical Constraints: %s
-Success Criteria: %s
-
-Generate a professional specification document with the following sections:
-1. Executive Summary
-2. Problem Statement
-3. Target Audience
-4. Functional Requirements (expand the core features into detailed requirements)
-5. Non-Functional Requirements
-6. Technical Architecture (based on constraints)
-7. Success Metrics
-8. Out of Scope
-9. Risks and Mitigations
-
-Be specific and actionable. Use clear, professional language.`,
-		m.data.ProjectName,
-		m.data.Description,
-		m.data.TargetUsers,
-		m.data.CoreFeatures,
-		m.data.TechnicalConstraints,
-		m.data.SuccessCriteria,
-	)
+	system, err := m.prompts.Render("intake_system", nil)
+	if err != nil {
+		return "", err
+	}
+	prompt, err := m.prompts.Render("intake_spec", m.data)
+	if err != nil {
+		return "", err
+	}
 
 	opts := llm.DefaultOptions()
-	opts.SystemPrompt = "You are a senior software architect creating detailed technical specifications. Be thorough but concise."
+	opts.SystemPrompt = system
 	opts.MaxTokens = 4096
+	opts = m.settings.options(opts)
+
+	ctx, cancel := m.settings.withTimeout(ctx)
+	defer cancel()
 
-	spec, err := m.provider.Complete(ctx, prompt, opts)
+	spec, gen, err := complete(ctx, m.provider, m.stream, prompt, opts)
+	if aborted(err) {
+		return "", err
+	}
 	if err != nil {
-		// Fallback to template
+		// Fallback to template, recording why
+		m.generatedBy = templateGeneration(err)
 		return m.generateTemplateSpec(), nil
 	}
 
-	m.data.GeneratedSpec = spec
-	return spec, nil
+	m.generatedBy = gen
+	m.data.GeneratedSpec = spec + gen.Footer()
+	return m.data.GeneratedSpec, nil
 }
 
 func (m *IntakeMode) generateTemplateSpec() string {
@@ -220,7 +281,8 @@ func (m *IntakeMode) generateTemplateSpec() string {
 	sb.WriteString(m.data.TechnicalConstraints + "\n\n")
 
 	sb.WriteString("## Success Criteria\n\n")
-	sb.WriteString(m.data.SuccessCriteria + "\n\n")
+	sb.WriteString(m.data.SuccessCriteria + "\n")
+	sb.WriteString(m.generatedBy.Footer())
 
 	m.data.GeneratedSpec = sb.String()
 	return m.data.GeneratedSpec
@@ -244,6 +306,11 @@ func (m *IntakeMode) SaveSpec() (string, error) {
 	return filename, nil
 }
 
+// GeneratedBy reports how the last specification was produced
+func (m *IntakeMode) GeneratedBy() Generation {
+	return m.generatedBy
+}
+
 // Data returns the collected intake data
 func (m *IntakeMode) Data() IntakeData {
 	return m.data
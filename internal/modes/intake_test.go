@@ -2,6 +2,7 @@ package modes
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -147,3 +148,71 @@ func TestGenerateSpecWithoutProvider(t *testing.T) {
 		t.Error("spec should contain project name")
 	}
 }
+
+func TestLoadIntakeData(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"answers.toml": "project_name = \"Demo\"\ndescription = \"A demo\"\ncore_features = \"\"\"\nLogin\nLogout\n\"\"\"\n",
+		"answers.yaml": "project_name: Demo\ndescription: A demo\ncore_features: |\n  Login\n  Logout\n",
+		"answers.json": `{"project_name": "Demo", "description": "A demo", "core_features": "Login\nLogout\n"}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			writeFile(t, path, content)
+
+			data, err := LoadIntakeData(path)
+			if err != nil {
+				t.Fatalf("LoadIntakeData() error = %v", err)
+			}
+			if data.ProjectName != "Demo" || data.Description != "A demo" {
+				t.Errorf("data = %+v", data)
+			}
+			if data.CoreFeatures != "Login\nLogout\n" {
+				t.Errorf("CoreFeatures = %q", data.CoreFeatures)
+			}
+		})
+	}
+}
+
+func TestLoadIntakeDataErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown.json", `{"project_name": "Demo", "projct_desc": "typo"}`},
+		{"missing.toml", `description = "no name"`},
+		{"answers.txt", "project_name: Demo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			writeFile(t, path, tt.content)
+			if _, err := LoadIntakeData(path); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSetDataGeneratesSpec(t *testing.T) {
+	m := NewIntakeMode(nil, t.TempDir())
+	m.SetData(IntakeData{ProjectName: "Scripted", CoreFeatures: "One\nTwo"})
+
+	if m.CurrentStep() != StepPreview {
+		t.Errorf("CurrentStep() = %v, want StepPreview", m.CurrentStep())
+	}
+	spec, err := m.GenerateSpec(context.Background())
+	if err != nil {
+		t.Fatalf("GenerateSpec() error = %v", err)
+	}
+	if !strings.Contains(spec, "Scripted") || !strings.Contains(spec, "- Two") {
+		t.Errorf("spec missing answers:\n%s", spec)
+	}
+	if _, err := m.SaveSpec(); err != nil {
+		t.Errorf("SaveSpec() error = %v", err)
+	}
+}
@@ -1,9 +1,249 @@
 package modes
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
+)
+
 // Mode interface definition
 type Mode interface {
 	Name() string
 	Description() string
 }
 
-// TODO: Implement INTAKE, REVIEW, CHANGE_ORDER, RESCUE modes
+// StreamHandler receives generated text as it arrives from the LLM
+type StreamHandler func(chunk string)
+
+// Mode names, as used in the [modes.<name>] config sections
+const (
+	ModeIntake      = "intake"
+	ModeReview      = "review"
+	ModeRescue      = "rescue"
+	ModeChangeOrder = "change_order"
+)
+
+// Settings tunes the LLM requests of a mode. Zero values keep the mode's
+// defaults.
+type Settings struct {
+	Model         string        // Model to request, empty for the provider's default
+	Temperature   *float64      // Sampling temperature, nil for the default
+	MaxTokens     int           // Most tokens per response
+	Timeout       time.Duration // Time a run may wait on the LLM, 0 for no limit
+	Prompts       *prompts.Set  // Prompt templates, nil for the built-in ones
+	ContextWindow int           // Tokens prompts are planned for, 0 for the model's window
+}
+
+// options returns opts with the settings applied
+func (s Settings) options(opts llm.Options) llm.Options {
+	if s.Model != "" {
+		opts.Model = s.Model
+	}
+	if s.Temperature != nil {
+		opts.Temperature = *s.Temperature
+	}
+	if s.MaxTokens > 0 {
+		opts.MaxTokens = s.MaxTokens
+	}
+	return opts
+}
+
+// withTimeout limits ctx to the run timeout, when one is set
+func (s Settings) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.Timeout)
+}
+
+// Resolver returns the provider and settings the named mode runs with. A
+// nil provider makes the mode use template output.
+type Resolver func(ctx context.Context, mode string) (llm.Provider, Settings, error)
+
+// TemplateSource is the Generation provider for built-in template output
+const TemplateSource = "template"
+
+// Generation records how a mode's output was produced
+type Generation struct {
+	Provider string    `json:"provider"`           // Provider that answered, or TemplateSource
+	Model    string    `json:"model,omitempty"`    // Model that answered, when known
+	Error    string    `json:"error,omitempty"`    // Why template output was used
+	Cached   bool      `json:"cached,omitempty"`   // Served from the response cache
+	Usage    llm.Usage `json:"usage"`              // Tokens used by every request of the run
+	Cost     float64   `json:"cost_usd,omitempty"` // Price of the run in US dollars, when known
+
+	cause error // Error behind Error, for errors.Is
+}
+
+// ErrNoLLM is the cause of template output when no provider is configured
+var ErrNoLLM = errors.New("no LLM provider configured")
+
+// templateGeneration records template output used because of err, or
+// because no provider is configured when err is nil
+func templateGeneration(err error) Generation {
+	if err == nil {
+		err = ErrNoLLM
+	}
+	return Generation{Provider: TemplateSource, Error: err.Error(), cause: err}
+}
+
+// Cause returns the error that made the mode use template output: ErrNoLLM
+// or the provider's error. It is nil for LLM output and for a Generation
+// decoded from JSON, which keeps only its message in Error.
+func (g Generation) Cause() error {
+	return g.cause
+}
+
+// Templated reports whether the output came from the built-in template
+func (g Generation) Templated() bool {
+	return g.Provider == TemplateSource
+}
+
+// UsageSummary describes the tokens and cost of the run, or returns ""
+// when no tokens were reported
+func (g Generation) UsageSummary() string {
+	if g.Usage.TotalTokens() == 0 {
+		return ""
+	}
+	summary := fmt.Sprintf("%d tokens (%d prompt, %d completion)", g.Usage.TotalTokens(), g.Usage.PromptTokens, g.Usage.CompletionTokens)
+	if g.Cost > 0 {
+		summary += fmt.Sprintf(", $%.4f", g.Cost)
+	}
+	return summary
+}
+
+// Footer returns a Markdown note naming where a document came from
+func (g Generation) Footer() string {
+	switch {
+	case g.Templated():
+		return fmt.Sprintf("\n---\n*Generated from template: %s*\n", g.Error)
+	case g.Model != "":
+		return fmt.Sprintf("\n---\n*Generated by %s (%s)*\n", g.Provider, g.Model)
+	}
+	return fmt.Sprintf("\n---\n*Generated by %s*\n", g.Provider)
+}
+
+// complete runs prompt against provider, streaming the output to handler
+// when one is set, and reports which provider and model answered
+func complete(ctx context.Context, provider llm.Provider, handler StreamHandler, prompt string, opts llm.Options) (string, Generation, error) {
+	gen := Generation{Provider: provider.Name(), Model: opts.Model}
+
+	if handler == nil {
+		resp, err := provider.Generate(ctx, opts.Request(prompt))
+		if err != nil {
+			return "", gen, err
+		}
+		return resp.Text, responseGeneration(provider, opts.Model, resp), nil
+	}
+
+	chunks, err := llm.Stream(ctx, provider, prompt, opts)
+	if err != nil {
+		return "", gen, err
+	}
+	var sb strings.Builder
+	for c := range chunks {
+		if c.Error != nil {
+			return sb.String(), gen, c.Error
+		}
+		if c.Done {
+			gen.update(c.Provider, c.Model)
+			gen.Cached = c.Cached
+			gen.Usage = c.Usage
+			gen.Cost = c.Cost
+		}
+		if c.Text != "" {
+			sb.WriteString(c.Text)
+			handler(c.Text)
+		}
+	}
+	return sb.String(), gen, nil
+}
+
+// extract fills out, a pointer to a struct, with structured data taken from
+// text by provider, asking model for it with the named template. The
+// returned Generation holds only the usage and cost.
+func extract(ctx context.Context, provider llm.Provider, model string, set *prompts.Set, tmpl, name, text string, out interface{}) (Generation, error) {
+	system, err := set.Render("extract_system", nil)
+	if err != nil {
+		return Generation{}, err
+	}
+	prompt, err := set.Render(tmpl, struct{ Document string }{text})
+	if err != nil {
+		return Generation{}, err
+	}
+
+	opts := llm.DefaultOptions()
+	opts.SystemPrompt = system
+	opts.Model = model
+	opts.Temperature = 0
+	opts.MaxTokens = 2048
+
+	resp, err := llm.GenerateJSON(ctx, provider, name, prompt, opts, out)
+	var gen Generation
+	if resp != nil {
+		gen.Usage, gen.Cost = resp.Usage, resp.Cost
+	}
+	return gen, err
+}
+
+// ErrNoConversation is returned by follow-up questions asked before an
+// LLM has produced the output they refer to
+var ErrNoConversation = errors.New("no LLM output to follow up on")
+
+// followUp sends question to provider in conv, which keeps the earlier
+// exchange as context, in the context window the exchange was planned for
+func followUp(ctx context.Context, provider llm.Provider, settings Settings, window int, conv *llm.Conversation, question string) (string, Generation, error) {
+	if conv == nil || provider == nil {
+		return "", Generation{}, ErrNoConversation
+	}
+	opts := llm.DefaultOptions()
+	opts.MaxTokens = 2048
+	opts = settings.options(opts)
+	opts.ContextWindow = newPlanner(window, opts.Model).window
+
+	ctx, cancel := settings.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := conv.Send(ctx, provider, question, opts)
+	if err != nil {
+		return "", Generation{Provider: provider.Name()}, err
+	}
+	return resp.Text, responseGeneration(provider, opts.Model, resp), nil
+}
+
+// responseGeneration records that provider produced resp for a request
+// for model
+func responseGeneration(provider llm.Provider, model string, resp *llm.GenerateResponse) Generation {
+	gen := Generation{Provider: provider.Name(), Model: model}
+	gen.update(resp.Provider, resp.Model)
+	gen.Cached = resp.Cached
+	gen.Usage = resp.Usage
+	gen.Cost = resp.Cost
+	return gen
+}
+
+// aborted reports whether err must stop a run rather than fall back to
+// template output: the budget is spent, or a prompt template is broken
+func aborted(err error) bool {
+	return errors.Is(err, llm.ErrBudgetExceeded) || errors.Is(err, prompts.ErrTemplate)
+}
+
+// addUsage adds the tokens and cost of an earlier request of the same run
+func (g *Generation) addUsage(o Generation) {
+	g.Usage = g.Usage.Add(o.Usage)
+	g.Cost += o.Cost
+}
+
+func (g *Generation) update(provider, model string) {
+	if provider != "" {
+		g.Provider = provider
+	}
+	if model != "" {
+		g.Model = model
+	}
+}
@@ -0,0 +1,182 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
+)
+
+// streamingProvider streams a fixed set of chunks
+type streamingProvider struct {
+	chunks []string
+}
+
+func (p *streamingProvider) Generate(ctx context.Context, req llm.GenerateRequest) (*llm.GenerateResponse, error) {
+	return &llm.GenerateResponse{Text: strings.Join(p.chunks, "")}, nil
+}
+
+func (p *streamingProvider) GenerateStream(ctx context.Context, req llm.GenerateRequest) (<-chan llm.GenerateChunk, error) {
+	ch := make(chan llm.GenerateChunk, len(p.chunks)+1)
+	for _, c := range p.chunks {
+		ch <- llm.GenerateChunk{Text: c}
+	}
+	ch <- llm.GenerateChunk{Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func (p *streamingProvider) Name() string                                 { return "Streaming" }
+func (p *streamingProvider) Available(ctx context.Context) bool           { return true }
+func (p *streamingProvider) Models(ctx context.Context) ([]string, error) { return nil, nil }
+
+func TestGenerateSpecStreams(t *testing.T) {
+	provider := &streamingProvider{chunks: []string{"# Spec", "\n\n", "Body"}}
+	intake := NewIntakeMode(provider, t.TempDir())
+	intake.SetData(IntakeData{ProjectName: "demo"})
+
+	var received []string
+	intake.SetStreamHandler(func(chunk string) {
+		received = append(received, chunk)
+	})
+
+	spec, err := intake.GenerateSpec(context.Background())
+	if err != nil {
+		t.Fatalf("GenerateSpec failed: %v", err)
+	}
+	if !strings.HasPrefix(spec, "# Spec\n\nBody") {
+		t.Errorf("Expected streamed spec, got %q", spec)
+	}
+	if len(received) != 3 {
+		t.Errorf("Expected 3 chunks, got %d", len(received))
+	}
+	if got := intake.GeneratedBy(); got.Provider != "Streaming" || got.Templated() {
+		t.Errorf("GeneratedBy() = %+v, want Streaming", got)
+	}
+}
+
+func TestTemplateFallbackIsReported(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+
+	review := NewReviewMode(&recordingProvider{err: errors.New("connection refused")}, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+
+	result, err := review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if !result.GeneratedBy.Templated() || result.GeneratedBy.Error != "connection refused" {
+		t.Errorf("GeneratedBy = %+v, want template with the provider error", result.GeneratedBy)
+	}
+	if !strings.Contains(result.FullReport, "Generated from template: connection refused") {
+		t.Errorf("Report should say it was generated from the template:\n%s", result.FullReport)
+	}
+}
+
+func TestTemplateCause(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+
+	review := NewReviewMode(nil, dir)
+	review.SetSpecFile(spec)
+	result, err := review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if !errors.Is(result.GeneratedBy.Cause(), ErrNoLLM) {
+		t.Errorf("Cause() = %v, want ErrNoLLM without a provider", result.GeneratedBy.Cause())
+	}
+
+	review = NewReviewMode(&recordingProvider{err: fmt.Errorf("ollama: %w", context.DeadlineExceeded)}, dir)
+	review.SetSpecFile(spec)
+	result, err = review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if !errors.Is(result.GeneratedBy.Cause(), context.DeadlineExceeded) {
+		t.Errorf("Cause() = %v, want the provider's timeout", result.GeneratedBy.Cause())
+	}
+}
+
+func TestBudgetAbortsRun(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+
+	review := NewReviewMode(&recordingProvider{err: fmt.Errorf("%w: test", llm.ErrBudgetExceeded)}, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+
+	if _, err := review.RunReview(context.Background()); !errors.Is(err, llm.ErrBudgetExceeded) {
+		t.Errorf("RunReview() error = %v, want ErrBudgetExceeded instead of template output", err)
+	}
+}
+
+func TestBudgetAbortsExtraction(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+	provider := &recordingProvider{jsonErr: fmt.Errorf("%w: test", llm.ErrBudgetExceeded)}
+
+	review := NewReviewMode(provider, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+	if _, err := review.RunReview(context.Background()); !errors.Is(err, llm.ErrBudgetExceeded) {
+		t.Errorf("RunReview() error = %v, want ErrBudgetExceeded instead of parsed findings", err)
+	}
+
+	co := NewChangeOrderMode(provider, dir)
+	co.SetSpecFile(spec)
+	co.SetCodebasePath(dir)
+	if _, err := co.DetectDrift(context.Background()); !errors.Is(err, llm.ErrBudgetExceeded) {
+		t.Errorf("DetectDrift() error = %v, want ErrBudgetExceeded instead of parsed changes", err)
+	}
+}
+
+func TestSettingsReachRequests(t *testing.T) {
+	provider := &recordingProvider{}
+	intake := NewIntakeMode(provider, t.TempDir())
+	intake.SetData(IntakeData{ProjectName: "demo"})
+
+	if _, err := intake.GenerateSpec(context.Background()); err != nil {
+		t.Fatalf("GenerateSpec failed: %v", err)
+	}
+	if provider.last.Model != "" || provider.last.Temperature == 0 || provider.deadline {
+		t.Errorf("Expected the defaults without settings, got %+v", provider.last)
+	}
+
+	temperature := 0.0
+	intake.SetSettings(Settings{Model: "llama3.2", Temperature: &temperature, MaxTokens: 1234, Timeout: time.Minute})
+	if _, err := intake.GenerateSpec(context.Background()); err != nil {
+		t.Fatalf("GenerateSpec failed: %v", err)
+	}
+	if req := provider.last; req.Model != "llama3.2" || req.Temperature != 0 || req.MaxTokens != 1234 {
+		t.Errorf("Expected the settings in the request, got %+v", req)
+	}
+	if !provider.deadline {
+		t.Error("Expected the timeout to bound the request")
+	}
+	if got := intake.GeneratedBy(); got.Model != "llama3.2" {
+		t.Errorf("GeneratedBy().Model = %q, want llama3.2", got.Model)
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "intake_system"+prompts.Ext), "Custom system prompt")
+	intake.SetSettings(Settings{Prompts: prompts.NewSet(dir)})
+	if _, err := intake.GenerateSpec(context.Background()); err != nil {
+		t.Fatalf("GenerateSpec failed: %v", err)
+	}
+	if provider.last.System != "Custom system prompt" {
+		t.Errorf("Expected the prompts from the settings, got system %q", provider.last.System)
+	}
+}
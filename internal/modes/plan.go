@@ -0,0 +1,368 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
+)
+
+// minCodeBudget is the fewest tokens of code worth sending in one batch
+const minCodeBudget = 512
+
+// maxNoteTokens caps the response for each batch of a map-reduce analysis
+const maxNoteTokens = 2048
+
+// sourceFile is a file, or part of one, to include in a prompt
+type sourceFile struct {
+	Path    string
+	Content string
+}
+
+func (f sourceFile) String() string {
+	return fmt.Sprintf("\n--- %s ---\n%s\n", f.Path, f.Content)
+}
+
+func joinSources(files []sourceFile) string {
+	var sb strings.Builder
+	for _, f := range files {
+		sb.WriteString(f.String())
+	}
+	return sb.String()
+}
+
+// collectSources reads the files under root accepted by include, skipping
+// VCS and dependency directories. It fails on the first directory or file
+// that cannot be read.
+func collectSources(root string, include func(path string) bool) ([]sourceFile, error) {
+	var files []sourceFile
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && skippedDir(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !include(path) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, sourceFile{Path: path, Content: string(data)})
+		return nil
+	})
+	return files, err
+}
+
+// readCodePaths reads the code files under each of paths, which may name
+// files or directories. Missing paths are skipped.
+func readCodePaths(paths []string) ([]sourceFile, error) {
+	var files []sourceFile
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			found, err := collectSources(path, isCodeFile)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, found...)
+		} else {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, sourceFile{Path: path, Content: string(data)})
+		}
+	}
+	return files, nil
+}
+
+// skippedDir reports whether a directory named name holds VCS data or
+// dependencies rather than the project's own code
+func skippedDir(name string) bool {
+	return name == ".git" || name == "vendor" || name == "node_modules"
+}
+
+// planner fits prompts into a model's context window
+type planner struct {
+	window int // Context window in tokens
+}
+
+// newPlanner plans for window tokens, or for the window of model when
+// window is zero
+func newPlanner(window int, model string) planner {
+	if window <= 0 {
+		window = llm.ContextWindow(model)
+	}
+	return planner{window: window}
+}
+
+// budget returns the tokens left for code once prompt is sent and
+// maxOutput tokens are reserved for the response. A tenth of the window is
+// kept back for errors in the token estimate.
+func (p planner) budget(prompt string, maxOutput int) int {
+	return (p.window-maxOutput)*9/10 - llm.EstimateTokens(prompt)
+}
+
+// maxOutput caps a response of requested tokens at half the window, so a
+// mode asking for long output still leaves room for its prompt
+func (p planner) maxOutput(requested int) int {
+	if requested > p.window/2 {
+		return p.window / 2
+	}
+	return requested
+}
+
+// batches groups files in order into batches of at most budget tokens,
+// splitting files that do not fit in a batch of their own
+func (p planner) batches(files []sourceFile, budget int) [][]sourceFile {
+	var batches [][]sourceFile
+	var current []sourceFile
+	used := 0
+	for _, f := range files {
+		parts := []sourceFile{f}
+		if llm.EstimateTokens(f.String()) > budget {
+			parts = splitSource(f, budget)
+		}
+		for _, part := range parts {
+			tokens := llm.EstimateTokens(part.String())
+			if used+tokens > budget && len(current) > 0 {
+				batches = append(batches, current)
+				current, used = nil, 0
+			}
+			current = append(current, part)
+			used += tokens
+		}
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// splitSource splits f on line boundaries into parts of at most budget
+// tokens, cutting lines that are too long on their own
+func splitSource(f sourceFile, budget int) []sourceFile {
+	maxChars := budget*4 - len(f.Path) - 32
+	if maxChars < 1 {
+		maxChars = 1
+	}
+
+	var chunks []string
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			chunks = append(chunks, sb.String())
+			sb.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(f.Content, "\n") {
+		for len(line) > maxChars {
+			flush()
+			chunks = append(chunks, line[:maxChars])
+			line = line[maxChars:]
+		}
+		if sb.Len()+len(line) > maxChars {
+			flush()
+		}
+		sb.WriteString(line)
+	}
+	flush()
+
+	parts := make([]sourceFile, len(chunks))
+	for i, chunk := range chunks {
+		parts[i] = sourceFile{
+			Path:    fmt.Sprintf("%s (part %d of %d)", f.Path, i+1, len(chunks)),
+			Content: chunk,
+		}
+	}
+	return parts
+}
+
+// analysis is a codebase analysis that runs as one prompt when the code
+// fits the context window, and otherwise takes notes on each batch of code
+// and produces its output from the notes. Prompts are rendered from the
+// named templates with analysisData.
+type analysis struct {
+	prompts *prompts.Set
+	spec    string // Specification the code is compared with, if any
+
+	single string // Template analysing all of the code
+	batch  string // Template taking notes on one batch
+	reduce string // Template producing the output from the notes
+
+	// With tools set, the model is sent the explore prompt with the list of
+	// files and reads the code it needs through the tools instead
+	tools   *llm.ToolRegistry
+	explore string
+
+	// With retrieve set, code too large for the single prompt is narrowed
+	// to the most relevant chunks that fit in budget tokens instead of
+	// being analysed in batches
+	retrieve func(ctx context.Context, budget int) ([]sourceFile, error)
+}
+
+// analysisData is the data analysis templates are rendered with
+type analysisData struct {
+	Spec  string // Specification the code is compared with
+	Code  string // Code, in the single and batch templates
+	Part  int    // Number of the batch from 1, in the batch template
+	Parts int    // Number of batches
+	Notes string // Notes on the batches, in the reduce and merge templates
+	Files string // Files of the codebase, in the explore template
+}
+
+// prompt renders the named template with d
+func (a analysis) prompt(name string, d analysisData) (string, error) {
+	d.Spec = a.spec
+	return a.prompts.Render(name, d)
+}
+
+// analyze runs a over files and returns the conversation of the final
+// pass, which ends with the output. Only the final pass streams to handler.
+// When a uses tools and the provider cannot, the code is sent instead.
+func analyze(ctx context.Context, provider llm.Provider, handler StreamHandler, p planner, a analysis, files []sourceFile, opts llm.Options) (*llm.Conversation, Generation, error) {
+	failed := Generation{Provider: provider.Name()}
+	opts.ContextWindow = p.window
+	if a.tools != nil {
+		prompt, err := a.prompt(a.explore, analysisData{Files: listSources(files)})
+		if err != nil {
+			return nil, failed, err
+		}
+		conv, gen, err := explore(ctx, provider, handler, a.tools, prompt, opts)
+		if err == nil || aborted(err) {
+			return conv, gen, err
+		}
+		fmt.Fprintf(os.Stderr, "warning: tool use failed, sending the code instead: %v\n", err)
+	}
+
+	opts.MaxTokens = p.maxOutput(opts.MaxTokens)
+	prompt, err := a.prompt(a.single, analysisData{Code: joinSources(files)})
+	if err != nil {
+		return nil, failed, err
+	}
+	if p.budget(prompt, opts.MaxTokens) >= 0 {
+		return finalPass(ctx, provider, handler, prompt, opts)
+	}
+
+	if a.retrieve != nil {
+		empty, err := a.prompt(a.single, analysisData{})
+		if err != nil {
+			return nil, failed, err
+		}
+		relevant, err := a.retrieve(ctx, p.budget(empty, opts.MaxTokens))
+		if aborted(err) {
+			return nil, failed, err
+		}
+		if err == nil && len(relevant) > 0 {
+			prompt, err := a.prompt(a.single, analysisData{Code: joinSources(relevant)})
+			if err != nil {
+				return nil, failed, err
+			}
+			return finalPass(ctx, provider, handler, prompt, opts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: retrieval failed, analysing all of the code in parts: %v\n", err)
+		}
+	}
+
+	noteOpts := opts
+	if noteOpts.MaxTokens > maxNoteTokens {
+		noteOpts.MaxTokens = maxNoteTokens
+	}
+	empty, err := a.prompt(a.batch, analysisData{Part: 1, Parts: 1})
+	if err != nil {
+		return nil, failed, err
+	}
+	budget := p.budget(empty, noteOpts.MaxTokens)
+	if budget < minCodeBudget {
+		return nil, failed, fmt.Errorf("context window of %d tokens is too small for this analysis", p.window)
+	}
+
+	batches := p.batches(files, budget)
+	var spent Generation
+	cached := true
+	notes := make([]sourceFile, len(batches))
+	for i, batch := range batches {
+		prompt, err := a.prompt(a.batch, analysisData{Code: joinSources(batch), Part: i + 1, Parts: len(batches)})
+		if err != nil {
+			return nil, failed, err
+		}
+		text, gen, err := complete(ctx, provider, nil, prompt, noteOpts)
+		if err != nil {
+			return nil, gen, fmt.Errorf("analysing part %d of %d: %w", i+1, len(batches), err)
+		}
+		spent.addUsage(gen)
+		cached = cached && gen.Cached
+		notes[i] = sourceFile{Path: fmt.Sprintf("Notes on part %d of %d", i+1, len(batches)), Content: text}
+	}
+
+	// Merge notes until they fit in the final prompt
+	for {
+		prompt, err = a.prompt(a.reduce, analysisData{Notes: joinSources(notes)})
+		if err != nil {
+			return nil, failed, err
+		}
+		if p.budget(prompt, opts.MaxTokens) >= 0 {
+			break
+		}
+
+		empty, err := a.prompts.Render("merge_notes", analysisData{})
+		if err != nil {
+			return nil, failed, err
+		}
+		groups := p.batches(notes, p.budget(empty, noteOpts.MaxTokens))
+		if len(groups) >= len(notes) {
+			return nil, failed, fmt.Errorf("analysis notes do not fit the context window of %d tokens", p.window)
+		}
+		merged := make([]sourceFile, len(groups))
+		for i, group := range groups {
+			mergePrompt, err := a.prompts.Render("merge_notes", analysisData{Notes: joinSources(group)})
+			if err != nil {
+				return nil, failed, err
+			}
+			text, gen, err := complete(ctx, provider, nil, mergePrompt, noteOpts)
+			if err != nil {
+				return nil, gen, fmt.Errorf("merging notes: %w", err)
+			}
+			spent.addUsage(gen)
+			cached = cached && gen.Cached
+			merged[i] = sourceFile{Path: fmt.Sprintf("Merged notes %d of %d", i+1, len(groups)), Content: text}
+		}
+		notes = merged
+	}
+
+	conv, gen, err := finalPass(ctx, provider, handler, prompt, opts)
+	gen.Cached = gen.Cached && cached
+	gen.addUsage(spent)
+	return conv, gen, err
+}
+
+// finalPass completes prompt and records the exchange as a conversation so
+// modes can ask follow-up questions
+func finalPass(ctx context.Context, provider llm.Provider, handler StreamHandler, prompt string, opts llm.Options) (*llm.Conversation, Generation, error) {
+	text, gen, err := complete(ctx, provider, handler, prompt, opts)
+	if err != nil {
+		return nil, gen, err
+	}
+	conv := llm.NewConversation(opts.SystemPrompt)
+	conv.AddUser(prompt)
+	conv.AddAssistant(text)
+	return conv, gen, nil
+}
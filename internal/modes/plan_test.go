@@ -0,0 +1,182 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+)
+
+// recordingProvider records every free-text prompt and answers with a
+// fixed text, or with fixed JSON (or jsonErr) for structured requests. A
+// provider with err fails every request with it.
+type recordingProvider struct {
+	prompts  []string
+	calls    int
+	json     string
+	jsonErr  error
+	err      error
+	last     llm.GenerateRequest
+	window   int  // Context window of the last free-text request
+	deadline bool // Whether the last request had a deadline
+}
+
+func (p *recordingProvider) Generate(ctx context.Context, req llm.GenerateRequest) (*llm.GenerateResponse, error) {
+	p.calls++
+	p.last = req
+	_, p.deadline = ctx.Deadline()
+	if p.err != nil {
+		return nil, p.err
+	}
+	if req.Schema != nil && p.jsonErr != nil {
+		return nil, p.jsonErr
+	}
+	text := p.json
+	if req.Schema == nil {
+		p.prompts = append(p.prompts, req.Prompt)
+		p.window = req.ContextWindow
+		text = fmt.Sprintf("Compliance Score: 90\nanswer %d", len(p.prompts))
+	}
+	return &llm.GenerateResponse{
+		Text:  text,
+		Usage: llm.Usage{PromptTokens: 10, CompletionTokens: 5},
+		Cost:  0.01,
+	}, nil
+}
+
+func (p *recordingProvider) Name() string                                 { return "recording" }
+func (p *recordingProvider) Available(ctx context.Context) bool           { return true }
+func (p *recordingProvider) Models(ctx context.Context) ([]string, error) { return nil, nil }
+
+// writeFile writes content to path, creating its directory, and fails the
+// test when it cannot
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlannerBatches(t *testing.T) {
+	files := []sourceFile{
+		{Path: "a.go", Content: strings.Repeat("a", 400)},
+		{Path: "b.go", Content: strings.Repeat("b", 400)},
+		{Path: "big.go", Content: strings.Repeat("line of code\n", 300)},
+	}
+
+	batches := planner{window: 100000}.batches(files, 250)
+	if len(batches) < 3 {
+		t.Fatalf("got %d batches, want the big file split across several", len(batches))
+	}
+
+	var content strings.Builder
+	for _, batch := range batches {
+		tokens := 0
+		for _, f := range batch {
+			tokens += llm.EstimateTokens(f.String())
+			if strings.HasPrefix(f.Path, "big.go") {
+				content.WriteString(f.Content)
+			}
+		}
+		if tokens > 250 {
+			t.Errorf("batch holds %d tokens, want at most 250", tokens)
+		}
+	}
+	if content.String() != files[2].Content {
+		t.Error("split parts should reassemble into the original file")
+	}
+}
+
+func TestReviewMapReduce(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec\n\nThe service must log requests.")
+	for i := 0; i < 4; i++ {
+		code := fmt.Sprintf("package main\n\n// file %d\n%s", i, strings.Repeat("var x = 1\n", 800))
+		writeFile(t, filepath.Join(dir, fmt.Sprintf("f%d.go", i)), code)
+	}
+
+	provider := &recordingProvider{}
+	review := NewReviewMode(provider, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+	review.SetContextWindow(8192)
+
+	result, err := review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if result.GeneratedBy.Templated() {
+		t.Fatalf("review fell back to the template: %s", result.GeneratedBy.Error)
+	}
+	if len(provider.prompts) < 3 {
+		t.Fatalf("got %d requests, want notes on several batches and a final pass", len(provider.prompts))
+	}
+	for i, prompt := range provider.prompts {
+		if tokens := llm.EstimateTokens(prompt); tokens > 8192-2048 {
+			t.Errorf("prompt %d is %d tokens, too large for the context window", i, tokens)
+		}
+	}
+	final := provider.prompts[len(provider.prompts)-1]
+	if !strings.Contains(final, "REVIEW NOTES:") || !strings.Contains(final, "The service must log requests.") {
+		t.Errorf("final prompt should combine the notes with the spec:\n%s", final)
+	}
+	if got, want := result.GeneratedBy.Usage.TotalTokens(), 15*provider.calls; got != want {
+		t.Errorf("Usage = %d tokens, want %d summed over every request", got, want)
+	}
+	if result.ComplianceScore == nil || *result.ComplianceScore != 90 {
+		t.Errorf("ComplianceScore = %v, want 90", result.ComplianceScore)
+	}
+}
+
+func TestReviewSinglePromptWhenItFits(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+
+	provider := &recordingProvider{}
+	review := NewReviewMode(provider, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+	review.SetSettings(Settings{ContextWindow: 32000})
+
+	if _, err := review.RunReview(context.Background()); err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if len(provider.prompts) != 1 || !strings.Contains(provider.prompts[0], "package main") {
+		t.Errorf("want one prompt containing the code, got %d", len(provider.prompts))
+	}
+	if provider.window != 32000 {
+		t.Errorf("ContextWindow = %d, want the planned 32000 so the provider does not truncate the prompt", provider.window)
+	}
+}
+
+func TestReadCodePathsReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+	files, err := readCodePaths([]string{dir, filepath.Join(dir, "missing")})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("readCodePaths() = %d files, %v; want main.go with the missing path skipped", len(files), err)
+	}
+
+	if err := os.Symlink(filepath.Join(dir, "gone.go"), filepath.Join(dir, "broken.go")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if _, err := readCodePaths([]string{dir}); err == nil {
+		t.Error("readCodePaths() should fail on a file it cannot read")
+	}
+	co := NewChangeOrderMode(nil, dir)
+	co.SetSpecFile(filepath.Join(dir, "main.go"))
+	co.SetCodebasePath(dir)
+	if _, err := co.DetectDrift(context.Background()); err == nil {
+		t.Error("DetectDrift() should fail on a file it cannot read")
+	}
+}
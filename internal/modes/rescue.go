@@ -9,25 +9,32 @@ import (
 	"time"
 
 	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
 )
 
 // RescueResult holds the rescue analysis results
 type RescueResult struct {
-	CodebasePath    string
-	FilesScanned    int
-	InferredSpec    string
-	AlignmentReport string
-	Architecture    string
-	Patterns        []string
-	Dependencies    []string
+	CodebasePath    string     `json:"codebase_path"`
+	FilesScanned    int        `json:"files_scanned"`
+	InferredSpec    string     `json:"inferred_spec"`
+	AlignmentReport string     `json:"alignment_report"`
+	Architecture    string     `json:"architecture,omitempty"`
+	Patterns        []string   `json:"patterns,omitempty"`
+	Dependencies    []string   `json:"dependencies,omitempty"`
+	GeneratedBy     Generation `json:"generated_by"`
 }
 
 // RescueMode handles the RESCUE workflow
 type RescueMode struct {
-	provider     llm.Provider
-	contractsDir string
-	reportsDir   string
-	result       RescueResult
+	provider      llm.Provider
+	prompts       *prompts.Set
+	settings      Settings
+	stream        StreamHandler
+	contextWindow int
+	useTools      bool
+	contractsDir  string
+	reportsDir    string
+	result        RescueResult
 }
 
 // NewRescueMode creates a new rescue mode
@@ -40,6 +47,7 @@ func NewRescueMode(provider llm.Provider, contractsDir, reportsDir string) *Resc
 	}
 	return &RescueMode{
 		provider:     provider,
+		prompts:      prompts.Default(),
 		contractsDir: contractsDir,
 		reportsDir:   reportsDir,
 	}
@@ -50,63 +58,98 @@ func (m *RescueMode) SetCodebasePath(path string) {
 	m.result.CodebasePath = path
 }
 
+// SetPrompts sets the prompt templates, which default to the built-in ones
+func (m *RescueMode) SetPrompts(set *prompts.Set) {
+	m.prompts = set
+}
+
+// SetSettings tunes the model and parameters of the mode's LLM requests,
+// and sets its prompt templates and context window when s carries them
+func (m *RescueMode) SetSettings(s Settings) {
+	m.settings = s
+	if s.Prompts != nil {
+		m.prompts = s.Prompts
+	}
+	if s.ContextWindow > 0 {
+		m.contextWindow = s.ContextWindow
+	}
+}
+
+// SetStreamHandler streams generated text to fn while the LLM responds
+func (m *RescueMode) SetStreamHandler(fn StreamHandler) {
+	m.stream = fn
+}
+
+// SetContextWindow sets the context window of the model in tokens. Zero
+// uses the window of the requested model.
+func (m *RescueMode) SetContextWindow(tokens int) {
+	m.contextWindow = tokens
+}
+
+// SetUseTools lets the model read the files it needs through tools instead
+// of being sent the whole codebase
+func (m *RescueMode) SetUseTools(enabled bool) {
+	m.useTools = enabled
+}
+
 // ScanCodebase scans and analyzes the codebase
 func (m *RescueMode) ScanCodebase(ctx context.Context) (*RescueResult, error) {
-	var codeContent strings.Builder
-	var fileList []string
+	ctx, cancel := m.settings.withTimeout(ctx)
+	defer cancel()
 
-	err := filepath.Walk(m.result.CodebasePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		// Skip hidden and vendor directories
-		if strings.Contains(path, "/.git/") || strings.Contains(path, "/vendor/") || strings.Contains(path, "/node_modules/") {
-			return nil
-		}
-		if isCodeFile(path) || isConfigFile(path) {
-			fileList = append(fileList, path)
-			data, _ := os.ReadFile(path)
-			// Limit file size
-			if len(data) < 10000 {
-				codeContent.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", path, string(data)))
-			}
-		}
-		return nil
+	files, err := collectSources(m.result.CodebasePath, func(path string) bool {
+		return isCodeFile(path) || isConfigFile(path)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan codebase: %w", err)
 	}
 
+	fileList := make([]string, len(files))
+	for i, f := range files {
+		fileList[i] = f.Path
+	}
 	m.result.FilesScanned = len(fileList)
 
 	if m.provider == nil {
+		m.result.GeneratedBy = templateGeneration(nil)
 		return m.generateTemplateRescue(fileList), nil
 	}
 
-	prompt := fmt.Sprintf(`Analyze this codebase and reverse-engineer a specification document.
-
-CODEBASE:
-%s
-
-Generate:
-1. A comprehensive specification document inferring the project's purpose, architecture, and requirements
-2. An alignment report showing what was discovered
-
-Format both as Markdown, separated by "---ALIGNMENT---"`, codeContent.String())
+	rescue := analysis{
+		prompts: m.prompts,
+		single:  "rescue",
+		batch:   "rescue_batch",
+		reduce:  "rescue_reduce",
+		explore: "rescue_explore",
+	}
+	if m.useTools {
+		rescue.tools = codebaseTools([]string{m.result.CodebasePath})
+	}
 
+	system, err := m.prompts.Render("rescue_system", nil)
+	if err != nil {
+		return nil, err
+	}
 	opts := llm.DefaultOptions()
-	opts.SystemPrompt = "You are a software architect reverse-engineering specifications from code."
+	opts.SystemPrompt = system
 	opts.MaxTokens = 8192
+	opts = m.settings.options(opts)
 
-	response, err := m.provider.Complete(ctx, prompt, opts)
+	conv, gen, err := analyze(ctx, m.provider, m.stream, newPlanner(m.contextWindow, opts.Model), rescue, files, opts)
+	if aborted(err) {
+		return nil, err
+	}
 	if err != nil {
+		m.result.GeneratedBy = templateGeneration(err)
 		return m.generateTemplateRescue(fileList), nil
 	}
 
+	response := conv.LastReply()
+	m.result.GeneratedBy = gen
 	parts := strings.Split(response, "---ALIGNMENT---")
-	m.result.InferredSpec = parts[0]
+	m.result.InferredSpec = parts[0] + gen.Footer()
 	if len(parts) > 1 {
-		m.result.AlignmentReport = parts[1]
+		m.result.AlignmentReport = parts[1] + gen.Footer()
 	} else {
 		m.result.AlignmentReport = m.generateAlignmentReport(fileList)
 	}
@@ -128,6 +171,7 @@ func (m *RescueMode) generateTemplateRescue(files []string) *RescueResult {
 	sb.WriteString("*Configure LLM for detailed architecture analysis*\n\n")
 	sb.WriteString("## Inferred Requirements\n\n")
 	sb.WriteString("*Configure LLM for requirement inference*\n")
+	sb.WriteString(m.result.GeneratedBy.Footer())
 
 	m.result.InferredSpec = sb.String()
 	m.result.AlignmentReport = m.generateAlignmentReport(files)
@@ -146,6 +190,7 @@ func (m *RescueMode) generateAlignmentReport(files []string) string {
 	sb.WriteString("- Review inferred spec for accuracy\n")
 	sb.WriteString("- Add missing requirements\n")
 	sb.WriteString("- Configure LLM for deeper analysis\n")
+	sb.WriteString(m.result.GeneratedBy.Footer())
 	return sb.String()
 }
 
@@ -0,0 +1,195 @@
+package modes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+)
+
+// embedChunkTokens is the size of the code chunks embedded for retrieval
+const embedChunkTokens = 256
+
+// CodeIndex finds the code most relevant to a piece of text, such as a
+// section of a spec, by comparing embeddings. The embeddings are kept in a
+// vector store so that only new or changed code is embedded again.
+type CodeIndex struct {
+	provider llm.Provider
+	model    string
+	store    *llm.VectorStore
+}
+
+// NewCodeIndex indexes code with embeddings from provider, kept in store.
+// An empty model uses the provider's default embedding model.
+func NewCodeIndex(provider llm.Provider, model string, store *llm.VectorStore) *CodeIndex {
+	return &CodeIndex{provider: provider, model: model, store: store}
+}
+
+// storeModel names the embedding model in the store, so that vectors from
+// another provider or model are never compared
+func (x *CodeIndex) storeModel() string {
+	model := x.model
+	if model == "" {
+		model = "default"
+	}
+	return x.provider.Name() + "/" + model
+}
+
+// Update indexes the code files under paths, embedding the chunks that
+// are new or changed, and saves the store. Chunks of files no longer
+// present are dropped. It returns the number of chunks embedded.
+func (x *CodeIndex) Update(ctx context.Context, paths []string) (int, error) {
+	known := x.store.Vectors(x.storeModel())
+	var entries []llm.VectorEntry
+	var pending []int // Entries to embed
+	files, err := readCodePaths(paths)
+	if err != nil {
+		return 0, fmt.Errorf("reading code: %w", err)
+	}
+	for _, f := range files {
+		for _, chunk := range chunkSource(f, embedChunkTokens) {
+			sum := sha256.Sum256([]byte(embedText(f.Path, chunk.Content)))
+			e := llm.VectorEntry{ID: chunk.Path, Source: f.Path, Hash: hex.EncodeToString(sum[:]), Text: chunk.Content}
+			if v, ok := known[e.Hash]; ok {
+				e.Vector = v
+			} else {
+				pending = append(pending, len(entries))
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	if len(pending) > 0 {
+		texts := make([]string, len(pending))
+		for i, n := range pending {
+			texts[i] = embedText(entries[n].Source, entries[n].Text)
+		}
+		resp, err := llm.EmbedTexts(ctx, x.provider, x.model, texts)
+		if err != nil {
+			return 0, fmt.Errorf("embedding code: %w", err)
+		}
+		for i, n := range pending {
+			entries[n].Vector = resp.Embeddings[i]
+		}
+	}
+	if err := x.store.Replace(x.storeModel(), entries); err != nil {
+		return 0, err
+	}
+	return len(pending), x.store.Save()
+}
+
+// Search returns the k chunks most relevant to query, best first
+func (x *CodeIndex) Search(ctx context.Context, query string, k int) ([]llm.VectorMatch, error) {
+	vectors, err := x.embedQueries(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return x.store.Search(vectors[0], k)
+}
+
+// relevant returns the chunks most relevant to queries that fit in budget
+// tokens, in file order. Queries take turns choosing their best remaining
+// chunk so that each one is covered.
+func (x *CodeIndex) relevant(ctx context.Context, queries []string, budget int) ([]sourceFile, error) {
+	vectors, err := x.embedQueries(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+	rankings := make([][]llm.VectorMatch, len(vectors))
+	for i, v := range vectors {
+		if rankings[i], err = x.store.Search(v, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	picked := map[string]bool{}
+	next := make([]int, len(rankings))
+	used := 0
+	for progress := true; progress; {
+		progress = false
+		for q, ranking := range rankings {
+			for next[q] < len(ranking) {
+				m := ranking[next[q]]
+				next[q]++
+				if picked[m.ID] {
+					continue
+				}
+				tokens := llm.EstimateTokens(sourceFile{Path: m.ID, Content: m.Text}.String())
+				if used+tokens > budget {
+					continue
+				}
+				picked[m.ID] = true
+				used += tokens
+				progress = true
+				break
+			}
+		}
+	}
+
+	var files []sourceFile
+	for _, e := range x.store.Entries {
+		if picked[e.ID] {
+			files = append(files, sourceFile{Path: e.ID, Content: e.Text})
+		}
+	}
+	return files, nil
+}
+
+// embedQueries embeds queries with the model the store was built with
+func (x *CodeIndex) embedQueries(ctx context.Context, queries []string) ([][]float32, error) {
+	if x.store.Model != x.storeModel() {
+		return nil, fmt.Errorf("the code index was built with %s, not %s; update it first", x.store.Model, x.storeModel())
+	}
+	resp, err := llm.EmbedTexts(ctx, x.provider, x.model, queries)
+	if err != nil {
+		return nil, fmt.Errorf("embedding query: %w", err)
+	}
+	return resp.Embeddings, nil
+}
+
+// embedText is the text embedded for a chunk of the file at path. The
+// path is included as it often says what the code is for.
+func embedText(path, content string) string {
+	return path + "\n" + content
+}
+
+// chunkSource splits f into chunks of at most tokens tokens, named by the
+// file and the lines they hold
+func chunkSource(f sourceFile, tokens int) []sourceFile {
+	parts := splitSource(f, tokens)
+	line := 1
+	for i := range parts {
+		lines := strings.Count(parts[i].Content, "\n")
+		end := line + lines - 1
+		if !strings.HasSuffix(parts[i].Content, "\n") {
+			end++
+		}
+		parts[i].Path = fmt.Sprintf("%s:%d-%d", f.Path, line, max(end, line))
+		line += lines
+	}
+	return parts
+}
+
+// specSections splits a Markdown spec at its headings, to retrieve the
+// code for each section separately
+func specSections(spec string) []string {
+	var sections []string
+	var sb strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(sb.String()); s != "" {
+			sections = append(sections, s)
+		}
+		sb.Reset()
+	}
+	for _, line := range strings.SplitAfter(spec, "\n") {
+		if strings.HasPrefix(line, "#") {
+			flush()
+		}
+		sb.WriteString(line)
+	}
+	flush()
+	return sections
+}
@@ -0,0 +1,108 @@
+package modes
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+)
+
+// keywordEmbedder is a recordingProvider that embeds text by which of a
+// few keywords it mentions
+type keywordEmbedder struct {
+	recordingProvider
+	embedded int
+}
+
+var embedKeywords = []string{"log", "note", "auth"}
+
+func (p *keywordEmbedder) Embed(ctx context.Context, req llm.EmbedRequest) (*llm.EmbedResponse, error) {
+	resp := &llm.EmbedResponse{Dimensions: len(embedKeywords)}
+	for _, text := range req.Input {
+		v := make([]float32, len(embedKeywords))
+		for i, k := range embedKeywords {
+			v[i] = float32(strings.Count(strings.ToLower(text), k))
+		}
+		resp.Embeddings = append(resp.Embeddings, v)
+	}
+	p.embedded += len(req.Input)
+	return resp, nil
+}
+
+func writeIndexedProject(t *testing.T) string {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "logger.go"), "package main\n\n// log writes a log line\nfunc log() {}\n")
+	writeFile(t, filepath.Join(dir, "notes.go"), "package main\n\n// note stores a note\nfunc note() {}\n")
+	writeFile(t, filepath.Join(dir, "auth.go"), "package main\n\n// auth checks auth tokens\nfunc auth() {}\n")
+	return dir
+}
+
+func TestCodeIndex(t *testing.T) {
+	dir := writeIndexedProject(t)
+	storePath := filepath.Join(dir, ".factory", "cache", "vectors.json")
+	provider := &keywordEmbedder{}
+	store, _ := llm.OpenVectorStore(storePath)
+	index := NewCodeIndex(provider, "", store)
+
+	embedded, err := index.Update(context.Background(), []string{dir})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if embedded != 3 {
+		t.Errorf("embedded %d chunks, want one per file", embedded)
+	}
+
+	writeFile(t, filepath.Join(dir, "auth.go"), "package main\n\n// auth checks auth tokens and logs\nfunc auth() {}\n")
+	store, _ = llm.OpenVectorStore(storePath)
+	index = NewCodeIndex(provider, "", store)
+	if embedded, err := index.Update(context.Background(), []string{dir}); err != nil || embedded != 1 {
+		t.Errorf("second Update embedded %d chunks (%v), want only the changed file", embedded, err)
+	}
+
+	matches, err := index.Search(context.Background(), "Notes must be stored", 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != filepath.Join(dir, "notes.go")+":1-4" {
+		t.Errorf("matches = %+v, want notes.go lines 1-4", matches)
+	}
+
+	other := NewCodeIndex(provider, "other-model", store)
+	if _, err := other.Search(context.Background(), "notes", 1); err == nil {
+		t.Error("searching with another model should fail until the index is updated")
+	}
+}
+
+func TestReviewRetrievesRelevantCode(t *testing.T) {
+	dir := writeIndexedProject(t)
+	spec := filepath.Join(t.TempDir(), "spec.md")
+	writeFile(t, spec, "# Spec\n\n## Logging\n\nEvery request is logged.\n\n## Notes\n\nNotes are stored.\n")
+	store, _ := llm.OpenVectorStore(filepath.Join(t.TempDir(), "vectors.json"))
+	// Far more code than fits the default window
+	writeFile(t, filepath.Join(dir, "auth.go"), strings.Repeat("// auth checks auth tokens\n", 6000))
+
+	provider := &keywordEmbedder{}
+	review := NewReviewMode(provider, t.TempDir())
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+	review.SetCodeIndex(NewCodeIndex(provider, "", store))
+
+	if _, err := review.RunReview(context.Background()); err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if len(provider.prompts) == 0 {
+		t.Fatal("no review prompt was sent")
+	}
+	prompt := provider.prompts[0]
+	if !strings.Contains(prompt, "func log()") || !strings.Contains(prompt, "func note()") {
+		t.Error("prompt should hold the code relevant to each spec section")
+	}
+	if llm.EstimateTokens(prompt) > llm.ContextWindow("") {
+		t.Errorf("prompt of %d tokens does not fit the context window", llm.EstimateTokens(prompt))
+	}
+	if strings.Contains(prompt, "part 1 of") {
+		t.Error("retrieval should replace reviewing the code in parts")
+	}
+}
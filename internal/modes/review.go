@@ -5,28 +5,39 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
 )
 
 // ReviewResult holds the analysis results
 type ReviewResult struct {
-	SpecFile        string
-	CodePaths       []string
-	ComplianceScore int
-	AlignedItems    []string
-	Deviations      []string
-	Recommendations []string
-	FullReport      string
+	SpecFile        string     `json:"spec_file"`
+	CodePaths       []string   `json:"code_paths"`
+	ComplianceScore *int       `json:"compliance_score"` // nil when no score was produced
+	AlignedItems    []string   `json:"aligned_items"`
+	Deviations      []string   `json:"deviations"`
+	Recommendations []string   `json:"recommendations"`
+	FullReport      string     `json:"full_report"`
+	GeneratedBy     Generation `json:"generated_by"`
 }
 
 // ReviewMode handles the REVIEW workflow
 type ReviewMode struct {
-	provider   llm.Provider
-	reportsDir string
-	result     ReviewResult
+	provider      llm.Provider
+	prompts       *prompts.Set
+	settings      Settings
+	stream        StreamHandler
+	contextWindow int
+	useTools      bool
+	index         *CodeIndex
+	reportsDir    string
+	result        ReviewResult
+	conv          *llm.Conversation // Exchange that produced the report
 }
 
 // NewReviewMode creates a new review mode
@@ -36,6 +47,7 @@ func NewReviewMode(provider llm.Provider, reportsDir string) *ReviewMode {
 	}
 	return &ReviewMode{
 		provider:   provider,
+		prompts:    prompts.Default(),
 		reportsDir: reportsDir,
 	}
 }
@@ -50,74 +62,167 @@ func (m *ReviewMode) SetCodePaths(paths []string) {
 	m.result.CodePaths = paths
 }
 
+// SetPrompts sets the prompt templates, which default to the built-in ones
+func (m *ReviewMode) SetPrompts(set *prompts.Set) {
+	m.prompts = set
+}
+
+// SetSettings tunes the model and parameters of the mode's LLM requests,
+// and sets its prompt templates and context window when s carries them
+func (m *ReviewMode) SetSettings(s Settings) {
+	m.settings = s
+	if s.Prompts != nil {
+		m.prompts = s.Prompts
+	}
+	if s.ContextWindow > 0 {
+		m.contextWindow = s.ContextWindow
+	}
+}
+
+// SetStreamHandler streams generated text to fn while the LLM responds
+func (m *ReviewMode) SetStreamHandler(fn StreamHandler) {
+	m.stream = fn
+}
+
+// SetContextWindow sets the context window of the model in tokens. Zero
+// uses the window of the requested model.
+func (m *ReviewMode) SetContextWindow(tokens int) {
+	m.contextWindow = tokens
+}
+
+// SetUseTools lets the model read the files it needs through tools instead
+// of being sent the whole codebase
+func (m *ReviewMode) SetUseTools(enabled bool) {
+	m.useTools = enabled
+}
+
+// SetCodeIndex narrows code too large for the context window to the
+// chunks idx finds most relevant to each section of the spec, instead of
+// reviewing all of it in parts
+func (m *ReviewMode) SetCodeIndex(idx *CodeIndex) {
+	m.index = idx
+}
+
 // RunReview performs the code review against the spec
 func (m *ReviewMode) RunReview(ctx context.Context) (*ReviewResult, error) {
+	m.conv = nil
+	m.result.ComplianceScore = nil
+	ctx, cancel := m.settings.withTimeout(ctx)
+	defer cancel()
+
 	// Read spec file
 	specContent, err := os.ReadFile(m.result.SpecFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read spec: %w", err)
 	}
 
-	// Read code files
-	var codeContent strings.Builder
-	for _, path := range m.result.CodePaths {
-		info, err := os.Stat(path)
-		if err != nil {
-			continue
-		}
-		if info.IsDir() {
-			filepath.Walk(path, func(p string, i os.FileInfo, e error) error {
-				if e != nil || i.IsDir() {
-					return nil
-				}
-				if isCodeFile(p) {
-					data, _ := os.ReadFile(p)
-					codeContent.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", p, string(data)))
-				}
-				return nil
-			})
-		} else {
-			data, _ := os.ReadFile(path)
-			codeContent.WriteString(fmt.Sprintf("\n--- %s ---\n%s\n", path, string(data)))
-		}
+	files, err := readCodePaths(m.result.CodePaths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read code: %w", err)
 	}
 
 	if m.provider == nil {
-		return m.generateTemplateReview(string(specContent), codeContent.String()), nil
+		m.result.GeneratedBy = templateGeneration(nil)
+		return m.generateTemplateReview(string(specContent), joinSources(files)), nil
 	}
 
-	prompt := fmt.Sprintf(`Analyze the following code against the specification and provide a compliance review.
-
-SPECIFICATION:
-%s
-
-CODE:
-%s
-
-Provide a structured review with:
-1. Compliance Score (0-100)
-2. Aligned Items (what matches the spec)
-3. Deviations Found (what doesn't match)
-4. Recommendations
-
-Format as Markdown.`, string(specContent), codeContent.String())
+	spec := string(specContent)
+	review := analysis{
+		prompts: m.prompts,
+		spec:    spec,
+		single:  "review",
+		batch:   "review_batch",
+		reduce:  "review_reduce",
+		explore: "review_explore",
+	}
+	if m.useTools {
+		review.tools = codebaseTools(m.result.CodePaths)
+	}
+	if m.index != nil {
+		review.retrieve = func(ctx context.Context, budget int) ([]sourceFile, error) {
+			if _, err := m.index.Update(ctx, m.result.CodePaths); err != nil {
+				return nil, err
+			}
+			return m.index.relevant(ctx, specSections(spec), budget)
+		}
+	}
 
+	system, err := m.prompts.Render("review_system", nil)
+	if err != nil {
+		return nil, err
+	}
 	opts := llm.DefaultOptions()
-	opts.SystemPrompt = "You are a code reviewer checking compliance with specifications."
+	opts.SystemPrompt = system
 	opts.MaxTokens = 4096
+	opts = m.settings.options(opts)
 
-	report, err := m.provider.Complete(ctx, prompt, opts)
+	conv, gen, err := analyze(ctx, m.provider, m.stream, newPlanner(m.contextWindow, opts.Model), review, files, opts)
+	if aborted(err) {
+		return nil, err
+	}
 	if err != nil {
-		return m.generateTemplateReview(string(specContent), codeContent.String()), nil
+		m.result.GeneratedBy = templateGeneration(err)
+		return m.generateTemplateReview(spec, joinSources(files)), nil
 	}
 
-	m.result.FullReport = report
-	m.parseReport(report)
+	m.conv = conv
+	report := conv.LastReply()
+	if err := m.extractFindings(ctx, report, &gen); err != nil {
+		return nil, err
+	}
+	m.result.GeneratedBy = gen
+	m.result.FullReport = report + gen.Footer()
 	return &m.result, nil
 }
 
+// Ask answers a follow-up question about the last review with the report
+// and the code it was based on in context. It returns ErrNoConversation
+// when the report did not come from an LLM.
+func (m *ReviewMode) Ask(ctx context.Context, question string) (string, Generation, error) {
+	return followUp(ctx, m.provider, m.settings, m.contextWindow, m.conv, question)
+}
+
+// Explain asks why the code deviates from the specification as described
+// by deviation, one of the review's findings
+func (m *ReviewMode) Explain(ctx context.Context, deviation string) (string, Generation, error) {
+	question, err := m.prompts.Render("review_explain", struct{ Deviation string }{deviation})
+	if err != nil {
+		return "", Generation{}, err
+	}
+	return m.Ask(ctx, question)
+}
+
+// reviewFindings is the structured form of a review report
+type reviewFindings struct {
+	ComplianceScore int      `json:"compliance_score" desc:"How far the code complies with the specification" min:"0" max:"100"`
+	AlignedItems    []string `json:"aligned_items" desc:"Requirements the code implements as specified"`
+	Deviations      []string `json:"deviations" desc:"Places where the code deviates from the specification"`
+	Recommendations []string `json:"recommendations" desc:"Changes that would improve compliance"`
+}
+
+// extractFindings fills the result from report with structured output,
+// falling back to parseReport when no valid findings are produced. It
+// fails only when the run must stop, as when the budget is exhausted.
+func (m *ReviewMode) extractFindings(ctx context.Context, report string, gen *Generation) error {
+	var findings reviewFindings
+	spent, err := extract(ctx, m.provider, m.settings.Model, m.prompts, "review_extract", "review", report, &findings)
+	gen.addUsage(spent)
+	if aborted(err) {
+		return err
+	}
+	if err != nil {
+		m.parseReport(report)
+		return nil
+	}
+	m.result.ComplianceScore = &findings.ComplianceScore
+	m.result.AlignedItems = findings.AlignedItems
+	m.result.Deviations = findings.Deviations
+	m.result.Recommendations = findings.Recommendations
+	return nil
+}
+
 func (m *ReviewMode) generateTemplateReview(spec, code string) *ReviewResult {
-	m.result.ComplianceScore = 75
+	m.result.ComplianceScore = nil
 	m.result.AlignedItems = []string{"Code structure exists", "Basic functionality present"}
 	m.result.Deviations = []string{"Manual review required for detailed analysis"}
 	m.result.Recommendations = []string{"Configure LLM for detailed analysis"}
@@ -126,7 +231,7 @@ func (m *ReviewMode) generateTemplateReview(spec, code string) *ReviewResult {
 	sb.WriteString("# Code Review Report\n\n")
 	sb.WriteString(fmt.Sprintf("*Generated: %s*\n\n", time.Now().Format("2006-01-02 15:04")))
 	sb.WriteString(fmt.Sprintf("**Spec File:** %s\n\n", m.result.SpecFile))
-	sb.WriteString(fmt.Sprintf("**Compliance Score:** %d/100\n\n", m.result.ComplianceScore))
+	sb.WriteString("**Compliance Score:** not scored without LLM analysis\n\n")
 	sb.WriteString("## Aligned Items\n\n")
 	for _, item := range m.result.AlignedItems {
 		sb.WriteString(fmt.Sprintf("- ✓ %s\n", item))
@@ -140,13 +245,23 @@ func (m *ReviewMode) generateTemplateReview(spec, code string) *ReviewResult {
 		sb.WriteString(fmt.Sprintf("- %s\n", item))
 	}
 
+	sb.WriteString(m.result.GeneratedBy.Footer())
+
 	m.result.FullReport = sb.String()
 	return &m.result
 }
 
+var complianceScorePattern = regexp.MustCompile(`(?i)compliance score(?:\s*\(0-100\))?\W*(\d{1,3})`)
+
+// parseReport takes the score from a free-text report when structured
+// extraction fails, leaving it unset when the report states none
 func (m *ReviewMode) parseReport(report string) {
-	// Simple parsing - in production would be more sophisticated
-	m.result.ComplianceScore = 80
+	m.result.ComplianceScore = nil
+	if match := complianceScorePattern.FindStringSubmatch(report); match != nil {
+		if score, err := strconv.Atoi(match[1]); err == nil && score <= 100 {
+			m.result.ComplianceScore = &score
+		}
+	}
 	m.result.AlignedItems = []string{"See full report"}
 	m.result.Deviations = []string{"See full report"}
 	m.result.Recommendations = []string{"See full report"}
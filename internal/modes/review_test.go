@@ -1,7 +1,14 @@
 package modes
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+	"github.com/ssdajoker/Code-Factory/internal/prompts"
 )
 
 // ReviewMode tests - placeholder for when ReviewMode is implemented
@@ -41,3 +48,149 @@ func TestReviewAnalysis(t *testing.T) {
 		})
 	}
 }
+
+func TestParseReportComplianceScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		want   int
+		scored bool
+	}{
+		{"bold heading", "## Review\n\n**Compliance Score:** 62/100\n", 62, true},
+		{"numbered list", "1. Compliance Score (0-100): 91", 91, true},
+		{"missing score", "No score here", 0, false},
+		{"out of range", "Compliance Score: 450", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewReviewMode(nil, "")
+			m.parseReport(tt.report)
+			score := m.result.ComplianceScore
+			if (score != nil) != tt.scored {
+				t.Fatalf("ComplianceScore = %v, want scored %v", score, tt.scored)
+			}
+			if score != nil && *score != tt.want {
+				t.Errorf("ComplianceScore = %d, want %d", *score, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateReviewIsUnscored(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+
+	review := NewReviewMode(nil, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+
+	result, err := review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if result.ComplianceScore != nil {
+		t.Errorf("ComplianceScore = %d, want no score from template output", *result.ComplianceScore)
+	}
+}
+
+func TestRunReviewStructuredFindings(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+
+	provider := &recordingProvider{json: `{"compliance_score": 64, "aligned_items": ["login"], "deviations": ["no logout", "no audit log"], "recommendations": ["add logout"]}`}
+	review := NewReviewMode(provider, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+
+	result, err := review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if result.ComplianceScore == nil || *result.ComplianceScore != 64 {
+		t.Errorf("ComplianceScore = %v, want 64 from the structured findings", result.ComplianceScore)
+	}
+	if len(result.Deviations) != 2 || result.Deviations[0] != "no logout" {
+		t.Errorf("Deviations = %v", result.Deviations)
+	}
+}
+
+func TestReviewFollowUpKeepsContext(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec\n\nThe service must log requests.")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+
+	provider := &recordingProvider{}
+	review := NewReviewMode(provider, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+
+	if _, _, err := review.Explain(context.Background(), "Requests are not logged"); !errors.Is(err, ErrNoConversation) {
+		t.Errorf("Explain before a review: error = %v, want ErrNoConversation", err)
+	}
+	if _, err := review.RunReview(context.Background()); err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+
+	answer, gen, err := review.Explain(context.Background(), "Requests are not logged")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if answer == "" || gen.Usage.TotalTokens() != 15 {
+		t.Errorf("Explain() = %q with %d tokens", answer, gen.Usage.TotalTokens())
+	}
+
+	messages := provider.last.Messages
+	if len(messages) != 3 {
+		t.Fatalf("follow-up sent %d messages, want the review exchange and the question", len(messages))
+	}
+	if messages[0].Role != llm.RoleUser || !strings.Contains(messages[0].Content, "package main") {
+		t.Error("first message should be the review prompt with the code")
+	}
+	if messages[1].Role != llm.RoleAssistant || !strings.Contains(messages[1].Content, "Compliance Score") {
+		t.Error("second message should be the review report")
+	}
+	if !strings.Contains(messages[2].Content, "Requests are not logged") {
+		t.Error("last message should ask about the deviation")
+	}
+	if provider.last.System == "" {
+		t.Error("follow-up should keep the reviewer system prompt")
+	}
+}
+
+func TestReviewPromptOverrides(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec\n\nThe service must log requests.")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+	templates := filepath.Join(dir, "templates")
+	writeFile(t, filepath.Join(templates, "review_system.tmpl"), "You review Go services.\n")
+	writeFile(t, filepath.Join(templates, "review.tmpl"), "Check {{.Code}} against {{.Spec}}\n")
+
+	provider := &recordingProvider{}
+	review := NewReviewMode(provider, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{filepath.Join(dir, "main.go")})
+	review.SetPrompts(prompts.NewSet(templates))
+
+	if _, err := review.RunReview(context.Background()); err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if !strings.HasPrefix(provider.prompts[0], "Check \n--- ") || !strings.HasSuffix(provider.prompts[0], "must log requests.") {
+		t.Errorf("prompt = %q, want the override", provider.prompts[0])
+	}
+	if _, _, err := review.Ask(context.Background(), "Why?"); err != nil || provider.last.System != "You review Go services." {
+		t.Errorf("system prompt = %q (%v), want the override", provider.last.System, err)
+	}
+
+	// A broken template stops the run instead of falling back to the template report
+	writeFile(t, filepath.Join(templates, "review.tmpl"), "{{.Cod}}")
+	review.SetPrompts(prompts.NewSet(templates))
+	if _, err := review.RunReview(context.Background()); !errors.Is(err, prompts.ErrTemplate) {
+		t.Errorf("RunReview with a broken template: error = %v, want ErrTemplate", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+func main() {
+	store := NewStore()
+
+	http.HandleFunc("POST /notes", func(w http.ResponseWriter, r *http.Request) {
+		var note Note
+		if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]int{"id": store.Add(note)})
+	})
+	http.HandleFunc("GET /notes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		note, ok := store.Get(r.PathValue("id"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(note)
+	})
+
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+)
+
+// Note is a stored note
+type Note struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// Store keeps notes in memory
+type Store struct {
+	mu    sync.Mutex
+	notes []Note
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// Add stores note and returns its ID
+func (s *Store) Add(note Note) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notes = append(s.notes, note)
+	return len(s.notes)
+}
+
+// Get returns the note with the given ID
+func (s *Store) Get(id string) (Note, bool) {
+	n, err := strconv.Atoi(id)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil || n < 1 || n > len(s.notes) {
+		return Note{}, false
+	}
+	return s.notes[n-1], true
+}
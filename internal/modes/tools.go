@@ -0,0 +1,229 @@
+package modes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+)
+
+// maxToolOutput caps the bytes a codebase tool returns to the model
+const maxToolOutput = 32 * 1024
+
+// maxGrepMatches caps the lines returned by the grep tool
+const maxGrepMatches = 100
+
+// codebase gives tools read-only access to the files under its roots
+type codebase struct {
+	roots []string // Absolute paths with symlinks resolved
+}
+
+func newCodebase(paths []string) codebase {
+	var c codebase
+	for _, path := range paths {
+		if root, err := realPath(path); err == nil {
+			c.roots = append(c.roots, root)
+		}
+	}
+	return c
+}
+
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// resolve returns the real path of path, refusing paths outside the roots
+func (c codebase) resolve(path string) (string, error) {
+	real, err := realPath(path)
+	if err != nil {
+		return "", fmt.Errorf("%s does not exist", path)
+	}
+	for _, root := range c.roots {
+		rel, err := filepath.Rel(root, real)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return real, nil
+		}
+	}
+	return "", fmt.Errorf("%s is outside the codebase", path)
+}
+
+type listDirArgs struct {
+	Path string `json:"path" desc:"Directory to list, as shown in the file list"`
+}
+
+type readFileArgs struct {
+	Path      string `json:"path" desc:"File to read, as shown in the file list"`
+	StartLine int    `json:"start_line,omitempty" desc:"First line to read, counting from 1" min:"1"`
+	EndLine   int    `json:"end_line,omitempty" desc:"Last line to read" min:"1"`
+}
+
+type grepArgs struct {
+	Pattern string `json:"pattern" desc:"Regular expression to search for, in RE2 syntax"`
+	Path    string `json:"path,omitempty" desc:"File or directory to search instead of the whole codebase"`
+}
+
+// codebaseTools offers the model read-only tools over the files under paths
+func codebaseTools(paths []string) *llm.ToolRegistry {
+	c := newCodebase(paths)
+	return llm.NewToolRegistry(
+		llm.NewTool("list_dir", "List the files and subdirectories of a directory in the codebase.", c.listDir),
+		llm.NewTool("read_file", "Read a file in the codebase, optionally only a range of lines.", c.readFile),
+		llm.NewTool("grep", "Search the codebase for lines matching a regular expression. Returns path:line: text for each match.", c.grep),
+	)
+}
+
+func (c codebase) listDir(ctx context.Context, args listDirArgs) (string, error) {
+	dir, err := c.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, e := range entries {
+		switch {
+		case e.IsDir() && skippedDir(e.Name()):
+		case e.IsDir():
+			fmt.Fprintf(&sb, "%s/\n", filepath.Join(args.Path, e.Name()))
+		default:
+			fmt.Fprintf(&sb, "%s\n", filepath.Join(args.Path, e.Name()))
+		}
+	}
+	return truncateOutput(sb.String()), nil
+}
+
+func (c codebase) readFile(ctx context.Context, args readFileArgs) (string, error) {
+	path, err := c.resolve(args.Path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if args.StartLine <= 0 && args.EndLine <= 0 {
+		return truncateOutput(string(data)), nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	start, end := args.StartLine, args.EndLine
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		return "", fmt.Errorf("%s has %d lines", args.Path, len(lines))
+	}
+	return truncateOutput(strings.Join(lines[start-1:end], "")), nil
+}
+
+func (c codebase) grep(ctx context.Context, args grepArgs) (string, error) {
+	re, err := regexp.Compile(args.Pattern)
+	if err != nil {
+		return "", err
+	}
+	roots := c.roots
+	if args.Path != "" {
+		root, err := c.resolve(args.Path)
+		if err != nil {
+			return "", err
+		}
+		roots = []string{root}
+	}
+
+	var matches []string
+	for _, root := range roots {
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if ctx.Err() != nil || len(matches) >= maxGrepMatches {
+				return filepath.SkipAll
+			}
+			if info.IsDir() {
+				if path != root && skippedDir(info.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || bytes.IndexByte(data, 0) >= 0 {
+				return nil
+			}
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for n := 1; scanner.Scan() && len(matches) < maxGrepMatches; n++ {
+				if re.MatchString(scanner.Text()) {
+					matches = append(matches, fmt.Sprintf("%s:%d: %s", c.display(path), n, scanner.Text()))
+				}
+			}
+			return nil
+		})
+	}
+	if len(matches) == 0 {
+		return "no matches", nil
+	}
+	sort.Strings(matches)
+	out := strings.Join(matches, "\n")
+	if len(matches) == maxGrepMatches {
+		out += fmt.Sprintf("\n[stopped after %d matches; narrow the pattern or path]", maxGrepMatches)
+	}
+	return truncateOutput(out), nil
+}
+
+// display returns path relative to the working directory when it is
+// inside it, matching how paths appear in the file list
+func (c codebase) display(path string) string {
+	if wd, err := realPath("."); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+func truncateOutput(s string) string {
+	if len(s) <= maxToolOutput {
+		return s
+	}
+	return s[:maxToolOutput] + fmt.Sprintf("\n[truncated %d bytes; read a range of lines to see more]", len(s)-maxToolOutput)
+}
+
+// listSources lists the paths and line counts of files for prompts that
+// leave the model to read the code through tools
+func listSources(files []sourceFile) string {
+	var sb strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&sb, "%s (%d lines)\n", f.Path, strings.Count(f.Content, "\n")+1)
+	}
+	return sb.String()
+}
+
+// explore sends prompt with tools so the model reads only the code it
+// needs, and returns the conversation like analyze. The answer is passed
+// to handler in one piece once the model has finished with the tools.
+func explore(ctx context.Context, provider llm.Provider, handler StreamHandler, tools *llm.ToolRegistry, prompt string, opts llm.Options) (*llm.Conversation, Generation, error) {
+	conv := llm.NewConversation(opts.SystemPrompt)
+	resp, err := conv.SendWithTools(ctx, provider, prompt, tools, opts)
+	if err != nil {
+		return nil, Generation{Provider: provider.Name(), Model: opts.Model}, err
+	}
+	if handler != nil {
+		handler(resp.Text)
+	}
+	return conv, responseGeneration(provider, opts.Model, resp), nil
+}
@@ -0,0 +1,95 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssdajoker/Code-Factory/internal/llm"
+)
+
+func TestCodebaseTools(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "project")
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(root, "pkg", "log.go"), "package pkg\n\nfunc Log() {}\n")
+	writeFile(t, filepath.Join(dir, "secret.txt"), "password")
+
+	tools := codebaseTools([]string{root})
+	call := func(name string, args interface{}) string {
+		data, _ := json.Marshal(args)
+		return tools.Call(context.Background(), llm.ToolCall{Name: name, Arguments: data})
+	}
+
+	if got := call("list_dir", listDirArgs{Path: root}); !strings.Contains(got, "pkg/") || strings.Contains(got, ".git") {
+		t.Errorf("list_dir = %q, want pkg/ without .git", got)
+	}
+	if got := call("read_file", readFileArgs{Path: filepath.Join(root, "pkg", "log.go"), StartLine: 3, EndLine: 3}); got != "func Log() {}\n" {
+		t.Errorf("read_file line 3 = %q", got)
+	}
+	if got := call("grep", grepArgs{Pattern: `func \w+`}); !strings.Contains(got, "log.go:3: func Log() {}") {
+		t.Errorf("grep = %q", got)
+	}
+	for _, path := range []string{filepath.Join(dir, "secret.txt"), filepath.Join(root, "..", "secret.txt")} {
+		if got := call("read_file", readFileArgs{Path: path}); !strings.Contains(got, "outside the codebase") {
+			t.Errorf("read_file(%s) = %q, want it refused", path, got)
+		}
+	}
+}
+
+// readingProvider reads the first file in the list with read_file, then
+// answers with a review
+type readingProvider struct {
+	recordingProvider
+	read string // Result of the read_file call
+}
+
+func (p *readingProvider) Generate(ctx context.Context, req llm.GenerateRequest) (*llm.GenerateResponse, error) {
+	if len(req.Tools) == 0 {
+		return p.recordingProvider.Generate(ctx, req)
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == llm.RoleTool {
+		p.read = last.Content
+		return &llm.GenerateResponse{Text: "Compliance Score: 80\nLogging is missing."}, nil
+	}
+	p.prompts = append(p.prompts, last.Content)
+	path := strings.Fields(last.Content[strings.Index(last.Content, "FILES:\n")+7:])[0]
+	args, _ := json.Marshal(readFileArgs{Path: path})
+	return &llm.GenerateResponse{
+		FinishReason: llm.FinishToolCalls,
+		ToolCalls:    []llm.ToolCall{{ID: "call_1", Name: "read_file", Arguments: args}},
+	}, nil
+}
+
+func TestReviewWithTools(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	writeFile(t, spec, "# Spec\n\nThe service must log requests.")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nfunc handle() {}\n")
+
+	provider := &readingProvider{}
+	review := NewReviewMode(provider, dir)
+	review.SetSpecFile(spec)
+	review.SetCodePaths([]string{dir})
+	review.SetUseTools(true)
+
+	result, err := review.RunReview(context.Background())
+	if err != nil {
+		t.Fatalf("RunReview failed: %v", err)
+	}
+	if len(provider.prompts) != 1 || strings.Contains(provider.prompts[0], "func handle") {
+		t.Fatalf("prompt should list the files without their code: %v", provider.prompts)
+	}
+	if !strings.Contains(provider.read, "func handle") {
+		t.Errorf("read_file returned %q, want the code of main.go", provider.read)
+	}
+	if result.ComplianceScore == nil || *result.ComplianceScore != 80 || !strings.Contains(result.FullReport, "Logging is missing.") {
+		t.Errorf("result = %v, %q", result.ComplianceScore, result.FullReport)
+	}
+}
@@ -0,0 +1,200 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ssdajoker/Code-Factory/internal/config"
+)
+
+// Context describes the project found in a directory
+type Context struct {
+	Root        string
+	Name        string
+	Languages   []string
+	IsGitRepo   bool
+	Remote      string // origin URL as configured in git
+	Repository  string // "owner/repo" when origin points at GitHub
+	Initialized bool   // .factory/config.toml already exists
+}
+
+// Language returns the primary detected language, or "" if none
+func (c *Context) Language() string {
+	if len(c.Languages) == 0 {
+		return ""
+	}
+	return c.Languages[0]
+}
+
+// languageMarkers maps manifest files to languages, in priority order
+var languageMarkers = []struct {
+	file     string
+	language string
+}{
+	{"go.mod", "Go"},
+	{"Cargo.toml", "Rust"},
+	{"tsconfig.json", "TypeScript"},
+	{"package.json", "JavaScript"},
+	{"pyproject.toml", "Python"},
+	{"requirements.txt", "Python"},
+	{"pom.xml", "Java"},
+	{"build.gradle", "Java"},
+	{"Gemfile", "Ruby"},
+	{"CMakeLists.txt", "C/C++"},
+}
+
+// Detect inspects root and returns its project context
+func Detect(root string) (*Context, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := &Context{
+		Root: abs,
+		Name: filepath.Base(abs),
+	}
+
+	for _, m := range languageMarkers {
+		if _, err := os.Stat(filepath.Join(abs, m.file)); err == nil && !contains(ctx.Languages, m.language) {
+			ctx.Languages = append(ctx.Languages, m.language)
+		}
+	}
+
+	if _, err := os.Stat(config.ProjectConfigPath(abs)); err == nil {
+		ctx.Initialized = true
+	}
+
+	if out, err := git(abs, "rev-parse", "--git-dir"); err == nil && out != "" {
+		ctx.IsGitRepo = true
+		if remote, err := git(abs, "config", "--get", "remote.origin.url"); err == nil {
+			ctx.Remote = remote
+			ctx.Repository = ParseGitHubRepo(remote)
+		}
+	}
+
+	return ctx, nil
+}
+
+// ParseGitHubRepo extracts "owner/repo" from a GitHub remote URL.
+// It returns "" for non-GitHub remotes.
+func ParseGitHubRepo(remote string) string {
+	remote = strings.TrimSpace(remote)
+	var path string
+	switch {
+	case strings.HasPrefix(remote, "git@github.com:"):
+		path = strings.TrimPrefix(remote, "git@github.com:")
+	case strings.Contains(remote, "github.com/"):
+		path = remote[strings.Index(remote, "github.com/")+len("github.com/"):]
+	default:
+		return ""
+	}
+
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// gitignoreEntries are appended to the project .gitignore by Init
+var gitignoreEntries = []string{
+	".factory/cache/",
+	".factory/temp/",
+}
+
+// Init creates the .factory layout under root and writes cfg.
+// It returns the paths it created or updated, relative to root.
+func Init(root string, cfg *config.ProjectConfig) ([]string, error) {
+	var created []string
+
+	dirs := []string{config.ProjectDirName, cfg.Paths.SpecsDir, cfg.Paths.ReportsDir}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(root, dir)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return created, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if dir != config.ProjectDirName {
+			if err := os.WriteFile(filepath.Join(path, ".gitkeep"), nil, 0644); err != nil {
+				return created, fmt.Errorf("failed to create %s/.gitkeep: %w", dir, err)
+			}
+		}
+		created = append(created, dir+"/")
+	}
+
+	if err := cfg.Save(root); err != nil {
+		return created, fmt.Errorf("failed to write project config: %w", err)
+	}
+	created = append(created, filepath.Join(config.ProjectDirName, "config.toml"))
+
+	updated, err := updateGitignore(root)
+	if err != nil {
+		return created, fmt.Errorf("failed to update .gitignore: %w", err)
+	}
+	if updated {
+		created = append(created, ".gitignore")
+	}
+
+	return created, nil
+}
+
+// updateGitignore appends missing Factory entries to root/.gitignore
+func updateGitignore(root string) (bool, error) {
+	path := filepath.Join(root, ".gitignore")
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+
+	lines := strings.Split(string(existing), "\n")
+	var missing []string
+	for _, entry := range gitignoreEntries {
+		if !contains(lines, entry) {
+			missing = append(missing, entry)
+		}
+	}
+	if len(missing) == 0 {
+		return false, nil
+	}
+
+	var sb strings.Builder
+	sb.Write(existing)
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		sb.WriteString("\n")
+	}
+	if len(existing) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("# Factory\n")
+	for _, entry := range missing {
+		sb.WriteString(entry + "\n")
+	}
+
+	return true, os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
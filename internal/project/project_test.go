@@ -0,0 +1,85 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ssdajoker/Code-Factory/internal/config"
+)
+
+func TestParseGitHubRepo(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"git@github.com:ssdajoker/Code-Factory.git", "ssdajoker/Code-Factory"},
+		{"https://github.com/ssdajoker/Code-Factory.git", "ssdajoker/Code-Factory"},
+		{"https://github.com/ssdajoker/Code-Factory", "ssdajoker/Code-Factory"},
+		{"ssh://git@github.com/ssdajoker/Code-Factory.git", "ssdajoker/Code-Factory"},
+		{"https://gitlab.com/ssdajoker/Code-Factory.git", ""},
+		{"https://github.com/ssdajoker", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			if got := ParseGitHubRepo(tt.remote); got != tt.want {
+				t.Errorf("ParseGitHubRepo(%q) = %q, want %q", tt.remote, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguages(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/demo\n"), 0644)
+	os.WriteFile(filepath.Join(root, "package.json"), []byte("{}"), 0644)
+
+	ctx, err := Detect(root)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if ctx.Language() != "Go" {
+		t.Errorf("Language() = %q, want %q", ctx.Language(), "Go")
+	}
+	if len(ctx.Languages) != 2 {
+		t.Errorf("Languages = %v, want 2 entries", ctx.Languages)
+	}
+	if ctx.Initialized {
+		t.Error("expected Initialized to be false")
+	}
+}
+
+func TestInit(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("bin/"), 0644)
+
+	cfg := config.NewProjectConfig("demo", config.GetDefault())
+	if _, err := Init(root, cfg); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	for _, dir := range []string{cfg.Paths.SpecsDir, cfg.Paths.ReportsDir} {
+		if _, err := os.Stat(filepath.Join(root, dir, ".gitkeep")); err != nil {
+			t.Errorf("expected %s/.gitkeep: %v", dir, err)
+		}
+	}
+
+	ctx, _ := Detect(root)
+	if !ctx.Initialized {
+		t.Error("expected Initialized after Init")
+	}
+
+	// Running twice must not duplicate .gitignore entries
+	if _, err := Init(root, cfg); err != nil {
+		t.Fatalf("second Init() error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if strings.Count(string(data), ".factory/cache/") != 1 {
+		t.Errorf(".gitignore has duplicate entries:\n%s", data)
+	}
+	if !strings.HasPrefix(string(data), "bin/\n") {
+		t.Errorf(".gitignore lost existing content:\n%s", data)
+	}
+}
@@ -0,0 +1,206 @@
+// Package prompts holds the prompt templates the modes send to the LLM.
+// The defaults are embedded in the binary; a file named <name>.tmpl in an
+// override directory replaces the template of that name, so teams can tune
+// prompts without forking. Templates use text/template syntax and may
+// include each other with {{template "name" .}}. A final newline in a
+// template file is ignored.
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Ext is the file extension of prompt templates
+const Ext = ".tmpl"
+
+//go:embed templates/*.tmpl
+var defaults embed.FS
+
+// ErrTemplate is wrapped by errors from loading or rendering templates
+var ErrTemplate = errors.New("prompt template error")
+
+// Template is a prompt template and where it was loaded from
+type Template struct {
+	Name   string
+	Origin string // Path of the override file, empty for the built-in default
+	Text   string
+}
+
+// BuiltIn reports whether t is the embedded default
+func (t Template) BuiltIn() bool {
+	return t.Origin == ""
+}
+
+// Set is the prompt templates in use: the defaults with any overrides
+// applied. It is loaded on first use and safe for concurrent use.
+type Set struct {
+	dirs []string
+
+	once      sync.Once
+	templates map[string]Template
+	tmpl      *template.Template
+	err       error
+}
+
+// NewSet returns the defaults overridden by the templates in dirs. Later
+// directories take precedence; empty and missing directories are skipped.
+func NewSet(dirs ...string) *Set {
+	return &Set{dirs: dirs}
+}
+
+var defaultSet = NewSet()
+
+// Default returns the embedded defaults without overrides
+func Default() *Set {
+	return defaultSet
+}
+
+// Render executes the named template with data
+func (s *Set) Render(name string, data interface{}) (string, error) {
+	if err := s.load(); err != nil {
+		return "", err
+	}
+	if _, ok := s.templates[name]; !ok {
+		return "", fmt.Errorf("%w: no template named %q", ErrTemplate, name)
+	}
+	var buf bytes.Buffer
+	if err := s.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTemplate, err)
+	}
+	return buf.String(), nil
+}
+
+// List returns every template in the set, sorted by name
+func (s *Set) List() ([]Template, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	list := make([]Template, 0, len(s.templates))
+	for _, t := range s.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Lookup returns the named template
+func (s *Set) Lookup(name string) (Template, error) {
+	if err := s.load(); err != nil {
+		return Template{}, err
+	}
+	t, ok := s.templates[name]
+	if !ok {
+		return Template{}, fmt.Errorf("unknown prompt template %q", name)
+	}
+	return t, nil
+}
+
+func (s *Set) load() error {
+	s.once.Do(func() {
+		s.templates, s.err = readTemplates(s.dirs)
+		if s.err != nil {
+			return
+		}
+		s.tmpl = template.New("").Option("missingkey=error")
+		for _, t := range s.templates {
+			if _, err := s.tmpl.New(t.Name).Parse(t.Text); err != nil {
+				s.err = fmt.Errorf("%w: %s: %v", ErrTemplate, t.location(), err)
+				return
+			}
+		}
+	})
+	return s.err
+}
+
+func (t Template) location() string {
+	if t.BuiltIn() {
+		return "built-in " + t.Name
+	}
+	return t.Origin
+}
+
+// readTemplates reads the defaults, then the overrides in dirs in order
+func readTemplates(dirs []string) (map[string]Template, error) {
+	templates := map[string]Template{}
+	entries, err := fs.ReadDir(defaults, "templates")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		data, err := fs.ReadFile(defaults, "templates/"+e.Name())
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(e.Name(), Ext)
+		templates[name] = Template{Name: name, Text: trimFinalNewline(string(data))}
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		paths, err := filepath.Glob(filepath.Join(dir, "*"+Ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrTemplate, err)
+			}
+			name := strings.TrimSuffix(filepath.Base(path), Ext)
+			templates[name] = Template{Name: name, Origin: path, Text: trimFinalNewline(string(data))}
+		}
+	}
+	return templates, nil
+}
+
+func trimFinalNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+// Eject writes the defaults of the named templates, or of all of them when
+// names is empty, to dir for editing. Existing files are kept unless force
+// is set. It returns the paths written.
+func Eject(dir string, names []string, force bool) ([]string, error) {
+	if len(names) == 0 {
+		list, err := Default().List()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range list {
+			names = append(names, t.Name)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, name := range names {
+		data, err := fs.ReadFile(defaults, "templates/"+name+Ext)
+		if err != nil {
+			return written, fmt.Errorf("unknown prompt template %q", name)
+		}
+		path := filepath.Join(dir, name+Ext)
+		if _, err := os.Stat(path); err == nil && !force {
+			continue
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
@@ -0,0 +1,108 @@
+package prompts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type analysisData struct {
+	Spec, Code string
+}
+
+func TestDefaultsRender(t *testing.T) {
+	list, err := Default().List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, tmpl := range list {
+		if !tmpl.BuiltIn() {
+			t.Errorf("%s should be built in", tmpl.Name)
+		}
+	}
+
+	got, err := Default().Render("review", analysisData{Spec: "SPEC", Code: "CODE"})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if !strings.HasPrefix(got, "Analyze the following code") || !strings.HasSuffix(got, "Format as Markdown.") {
+		t.Errorf("review = %q, want the comment and final newline dropped", got)
+	}
+	if !strings.Contains(got, "SPECIFICATION:\nSPEC\n\nCODE:\nCODE\n\nProvide a structured review") {
+		t.Errorf("review = %q, want the instructions included", got)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	user, project := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(user, "review_system.tmpl"), []byte("User reviewer.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "review_system.tmpl"), []byte("Project reviewer.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project, "review_instructions.tmpl"), []byte("Answer in French."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set := NewSet(user, "", project, filepath.Join(project, "missing"))
+
+	if got, _ := set.Render("review_system", nil); got != "Project reviewer." {
+		t.Errorf("review_system = %q, want the project override", got)
+	}
+	if got, _ := set.Render("review", analysisData{}); !strings.HasSuffix(got, "\n\nAnswer in French.") {
+		t.Errorf("review = %q, want the overridden instructions", got)
+	}
+	tmpl, err := set.Lookup("review_system")
+	if err != nil || tmpl.Origin != filepath.Join(project, "review_system.tmpl") {
+		t.Errorf("Lookup = %+v, %v", tmpl, err)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "review.tmpl"), []byte("{{.Missing}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set := NewSet(dir)
+	if _, err := set.Render("review", analysisData{}); !errors.Is(err, ErrTemplate) {
+		t.Errorf("unknown field: error = %v, want ErrTemplate", err)
+	}
+	if _, err := set.Render("nonexistent", nil); !errors.Is(err, ErrTemplate) {
+		t.Errorf("unknown template: error = %v, want ErrTemplate", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "review.tmpl"), []byte("{{if}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSet(dir).Render("review_system", nil); !errors.Is(err, ErrTemplate) || !strings.Contains(err.Error(), "review.tmpl") {
+		t.Errorf("parse error: error = %v, want ErrTemplate naming the file", err)
+	}
+}
+
+func TestEject(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".factory", "templates")
+	written, err := Eject(dir, []string{"review", "review_system"}, false)
+	if err != nil || len(written) != 2 {
+		t.Fatalf("Eject = %v, %v", written, err)
+	}
+	if err := os.WriteFile(written[0], []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if written, _ := Eject(dir, nil, false); len(written) == 0 || slices.Contains(written, filepath.Join(dir, "review.tmpl")) {
+		t.Errorf("ejecting all wrote %v, want the rest without overwriting review.tmpl", written)
+	}
+	if _, err := Eject(dir, []string{"nonexistent"}, false); err == nil {
+		t.Error("ejecting an unknown template should fail")
+	}
+
+	// Ejected templates render exactly as the defaults
+	got, _ := NewSet(dir).Render("review_system", nil)
+	want, _ := Default().Render("review_system", nil)
+	if got != want {
+		t.Errorf("ejected review_system = %q, want %q", got, want)
+	}
+}
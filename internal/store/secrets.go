@@ -22,6 +22,11 @@ const (
 	argon2KeyLen  = 32
 )
 
+// Well-known secret keys
+const (
+	KeyGitHubToken = "github_token"
+)
+
 var (
 	ErrSecretNotFound = errors.New("secret not found")
 	ErrKeyringUnavailable = errors.New("keyring unavailable")
@@ -88,17 +93,25 @@ type FileStore struct {
 // NewFileStore creates a new file-based encrypted store
 // password should be user-provided or a high-entropy random key
 func NewFileStore(password string) (*FileStore, error) {
-	home, err := os.UserHomeDir()
+	dir, err := SecretsDir()
 	if err != nil {
 		return nil, err
 	}
-	dir := filepath.Join(home, ".factory", "secrets")
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return nil, err
 	}
 	return &FileStore{dir: dir, password: password}, nil
 }
 
+// SecretsDir returns the directory used by FileStore
+func SecretsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".factory", "secrets"), nil
+}
+
 // deriveKey uses Argon2id to derive an encryption key from password
 func (f *FileStore) deriveKey(salt []byte) []byte {
 	return argon2.IDKey(
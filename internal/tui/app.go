@@ -1,9 +1,12 @@
 package tui
 
 import (
+        "context"
         "fmt"
 
         tea "github.com/charmbracelet/bubbletea"
+        "github.com/ssdajoker/Code-Factory/internal/llm"
+        "github.com/ssdajoker/Code-Factory/internal/modes"
         "github.com/ssdajoker/Code-Factory/internal/tui/views"
 )
 
@@ -28,6 +31,10 @@ type Model struct {
         width           int
         height          int
         err             error
+        resolve         modes.Resolver
+        resolving       string // Mode whose provider is being resolved
+        contractsDir    string
+        reportsDir      string
         intakeView      *views.IntakeView
         reviewView      *views.ReviewView
         rescueView      *views.RescueView
@@ -45,11 +52,16 @@ var menuItems = []string{
         "❌ Quit",
 }
 
-// New creates a new TUI model
-func New() Model {
+// New creates a new TUI model. resolve picks the provider and settings of
+// each mode; a nil resolver runs the modes without an LLM. Specs are kept
+// in contractsDir and reports written to reportsDir.
+func New(resolve modes.Resolver, contractsDir, reportsDir string) Model {
         return Model{
-                currentView: ViewHome,
-                menuIndex:   0,
+                currentView:  ViewHome,
+                menuIndex:    0,
+                resolve:      resolve,
+                contractsDir: contractsDir,
+                reportsDir:   reportsDir,
         }
 }
 
@@ -97,6 +109,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
         }
 
         switch msg := msg.(type) {
+        case modeResolvedMsg:
+                return m.openMode(msg)
         case tea.KeyMsg:
                 return m.handleKey(msg)
         case tea.WindowSizeMsg:
@@ -132,38 +146,95 @@ func (m Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) selectMenuItem() (tea.Model, tea.Cmd) {
+        m.err = nil
         switch m.menuIndex {
         case 0:
                 m.currentView = ViewInit
         case 1:
+                return m.startMode(modes.ModeIntake)
+        case 2:
+                return m.startMode(modes.ModeReview)
+        case 3:
+                return m.startMode(modes.ModeRescue)
+        case 4:
+                return m.startMode(modes.ModeChangeOrder)
+        case 5:
+                m.currentView = ViewSettings
+        case 6:
+                m.quitting = true
+                return m, tea.Quit
+        }
+        return m, nil
+}
+
+// modeResolvedMsg carries the provider and settings resolved for a mode
+type modeResolvedMsg struct {
+        mode     string
+        provider llm.Provider
+        settings modes.Settings
+        err      error
+}
+
+// startMode resolves the provider and settings of mode in the background,
+// as detection may wait on the network; openMode shows the mode once they
+// arrive
+func (m Model) startMode(mode string) (tea.Model, tea.Cmd) {
+        if m.resolving != "" {
+                return m, nil
+        }
+        m.resolving = mode
+        resolve := m.resolve
+        return m, func() tea.Msg {
+                provider, settings, err := resolveMode(resolve, mode)
+                return modeResolvedMsg{mode: mode, provider: provider, settings: settings, err: err}
+        }
+}
+
+// openMode switches to the view of a resolved mode
+func (m Model) openMode(msg modeResolvedMsg) (tea.Model, tea.Cmd) {
+        m.resolving = ""
+        if msg.err != nil {
+                m.err = msg.err
+                return m, nil
+        }
+        switch msg.mode {
+        case modes.ModeIntake:
                 m.currentView = ViewIntake
-                iv := views.NewIntakeView(nil, "contracts")
+                iv := views.NewIntakeView(msg.provider, m.contractsDir)
+                iv.SetSettings(msg.settings)
                 m.intakeView = &iv
                 return m, m.intakeView.Init()
-        case 2:
+        case modes.ModeReview:
                 m.currentView = ViewReview
-                rv := views.NewReviewView(nil, "contracts", "reports")
+                rv := views.NewReviewView(msg.provider, m.contractsDir, m.reportsDir)
+                rv.SetSettings(msg.settings)
                 m.reviewView = &rv
                 return m, m.reviewView.Init()
-        case 3:
+        case modes.ModeRescue:
                 m.currentView = ViewRescue
-                rv := views.NewRescueView(nil, "contracts", "reports")
+                rv := views.NewRescueView(msg.provider, m.contractsDir, m.reportsDir)
+                rv.SetSettings(msg.settings)
                 m.rescueView = &rv
                 return m, m.rescueView.Init()
-        case 4:
+        case modes.ModeChangeOrder:
                 m.currentView = ViewChangeOrder
-                cv := views.NewChangeOrderView(nil, "contracts")
+                cv := views.NewChangeOrderView(msg.provider, m.contractsDir)
+                cv.SetSettings(msg.settings)
                 m.changeOrderView = &cv
                 return m, m.changeOrderView.Init()
-        case 5:
-                m.currentView = ViewSettings
-        case 6:
-                m.quitting = true
-                return m, tea.Quit
         }
         return m, nil
 }
 
+// resolveMode returns the provider and settings of mode, or no provider
+// when there is no resolver
+func resolveMode(resolve modes.Resolver, mode string) (llm.Provider, modes.Settings, error) {
+        if resolve == nil {
+                return nil, modes.Settings{}, nil
+        }
+        return resolve(context.Background(), mode)
+}
+
 // View implements tea.Model
 func (m Model) View() string {
         if m.quitting {
@@ -199,6 +270,14 @@ func (m Model) viewHome() string {
         s += "\n\n"
         s += RenderMenu(menuItems, m.menuIndex)
         s += "\n\n"
+        if m.resolving != "" {
+                s += StyleSubtle.Render("  Connecting to LLM...")
+                s += "\n\n"
+        }
+        if m.err != nil {
+                s += StyleError.Render("  " + m.err.Error())
+                s += "\n\n"
+        }
         s += StyleSubtle.Render("↑/↓: navigate • enter: select • q: quit")
         s += "\n"
         return s
@@ -225,8 +304,8 @@ func (m Model) viewPlaceholder() string {
 }
 
 // Run starts the TUI application
-func Run() error {
-        p := tea.NewProgram(New(), tea.WithAltScreen())
+func Run(resolve modes.Resolver, contractsDir, reportsDir string) error {
+        p := tea.NewProgram(New(resolve, contractsDir, reportsDir), tea.WithAltScreen())
         _, err := p.Run()
         if err != nil {
                 return fmt.Errorf("error running TUI: %w", err)
@@ -234,11 +313,17 @@ func Run() error {
         return nil
 }
 
-// RunIntake starts the INTAKE mode TUI directly
-func RunIntake() error {
-        iv := views.NewIntakeView(nil, "contracts")
+// RunIntake starts the INTAKE mode TUI directly, writing the spec to
+// contractsDir
+func RunIntake(resolve modes.Resolver, contractsDir string) error {
+        provider, settings, err := resolveMode(resolve, modes.ModeIntake)
+        if err != nil {
+                return err
+        }
+        iv := views.NewIntakeView(provider, contractsDir)
+        iv.SetSettings(settings)
         p := tea.NewProgram(iv, tea.WithAltScreen())
-        _, err := p.Run()
+        _, err = p.Run()
         if err != nil {
                 return fmt.Errorf("error running intake: %w", err)
         }
@@ -85,6 +85,14 @@ func NewChangeOrderView(provider llm.Provider, contractsDir string) ChangeOrderV
 	}
 }
 
+// SetSettings applies the model and parameters configured for the mode
+func (v *ChangeOrderView) SetSettings(s modes.Settings) {
+	v.changeOrder.SetSettings(s)
+	if s.Model != "" && v.llmStatus != "No LLM" {
+		v.llmStatus += " (" + s.Model + ")"
+	}
+}
+
 // Init implements tea.Model
 func (v ChangeOrderView) Init() tea.Cmd {
 	return v.filePicker.Init()
@@ -276,6 +284,7 @@ func (v ChangeOrderView) View() string {
 		result := v.changeOrder.Result()
 		sb.WriteString(successStyle.Render("Drift Analysis Complete!"))
 		sb.WriteString("\n\n")
+		sb.WriteString(usageLine(result.GeneratedBy))
 
 		for i, change := range result.Changes {
 			prefix := "  "
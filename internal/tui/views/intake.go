@@ -24,6 +24,15 @@ var (
 	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
 )
 
+// usageLine renders the LLM usage of a run, or "" when none was reported
+func usageLine(gen modes.Generation) string {
+	usage := gen.UsageSummary()
+	if usage == "" {
+		return ""
+	}
+	return blurredStyle.Render("LLM usage: "+usage) + "\n\n"
+}
+
 // IntakeView is the TUI view for INTAKE mode
 type IntakeView struct {
 	intake      *modes.IntakeMode
@@ -83,6 +92,14 @@ func NewIntakeView(provider llm.Provider, contractsDir string) IntakeView {
 	}
 }
 
+// SetSettings applies the model and parameters configured for the mode
+func (v *IntakeView) SetSettings(s modes.Settings) {
+	v.intake.SetSettings(s)
+	if s.Model != "" && v.llmStatus != "No LLM" {
+		v.llmStatus += " (" + s.Model + ")"
+	}
+}
+
 // Init implements tea.Model
 func (v IntakeView) Init() tea.Cmd {
 	return textinput.Blink
@@ -310,6 +327,7 @@ func (v IntakeView) viewPreview() string {
 
 	sb.WriteString(titleStyle.Render("📄 Preview Specification"))
 	sb.WriteString("\n\n")
+	sb.WriteString(usageLine(v.intake.GeneratedBy()))
 
 	// Show truncated preview
 	spec := v.intake.Data().GeneratedSpec
@@ -76,6 +76,14 @@ func NewRescueView(provider llm.Provider, contractsDir, reportsDir string) Rescu
 	}
 }
 
+// SetSettings applies the model and parameters configured for the mode
+func (v *RescueView) SetSettings(s modes.Settings) {
+	v.rescue.SetSettings(s)
+	if s.Model != "" && v.llmStatus != "No LLM" {
+		v.llmStatus += " (" + s.Model + ")"
+	}
+}
+
 // Init implements tea.Model
 func (v RescueView) Init() tea.Cmd {
 	return v.filePicker.Init()
@@ -201,6 +209,7 @@ func (v RescueView) View() string {
 		sb.WriteString(successStyle.Render("Scan Complete!"))
 		sb.WriteString("\n\n")
 		sb.WriteString(fmt.Sprintf("Files scanned: %d\n\n", result.FilesScanned))
+		sb.WriteString(usageLine(result.GeneratedBy))
 
 		sb.WriteString(focusedStyle.Render("Inferred Specification Preview:"))
 		sb.WriteString("\n")
@@ -81,6 +81,14 @@ func NewReviewView(provider llm.Provider, contractsDir, reportsDir string) Revie
         }
 }
 
+// SetSettings applies the model and parameters configured for the mode
+func (v *ReviewView) SetSettings(s modes.Settings) {
+        v.review.SetSettings(s)
+        if s.Model != "" && v.llmStatus != "No LLM" {
+                v.llmStatus += " (" + s.Model + ")"
+        }
+}
+
 // Init implements tea.Model
 func (v ReviewView) Init() tea.Cmd {
         return v.filePicker.Init()
@@ -230,7 +238,12 @@ func (v ReviewView) View() string {
                 sb.WriteString(successStyle.Render("Analysis Complete!"))
                 sb.WriteString("\n\n")
                 sb.WriteString(focusedStyle.Render("Compliance Score: "))
-                sb.WriteString(fmt.Sprintf("%d/100\n\n", result.ComplianceScore))
+                if result.ComplianceScore != nil {
+                        sb.WriteString(fmt.Sprintf("%d/100\n\n", *result.ComplianceScore))
+                } else {
+                        sb.WriteString("not scored\n\n")
+                }
+                sb.WriteString(usageLine(result.GeneratedBy))
 
                 // Show truncated report
                 lines := strings.Split(result.FullReport, "\n")
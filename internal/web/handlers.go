@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ssdajoker/Code-Factory/internal/llm"
 	"github.com/ssdajoker/Code-Factory/internal/modes"
 )
 
@@ -16,16 +17,27 @@ import (
 type Handlers struct {
 	contractsDir string
 	reportsDir   string
+	resolve      modes.Resolver
 }
 
-// NewHandlers creates new handlers
-func NewHandlers(contractsDir, reportsDir string) *Handlers {
+// NewHandlers creates new handlers. resolve picks the provider and
+// settings of each mode; with a nil resolver the modes run without an LLM.
+func NewHandlers(contractsDir, reportsDir string, resolve modes.Resolver) *Handlers {
 	return &Handlers{
 		contractsDir: contractsDir,
 		reportsDir:   reportsDir,
+		resolve:      resolve,
 	}
 }
 
+// resolveMode returns the provider and settings of mode
+func (h *Handlers) resolveMode(ctx context.Context, mode string) (llm.Provider, modes.Settings, error) {
+	if h.resolve == nil {
+		return nil, modes.Settings{}, nil
+	}
+	return h.resolve(ctx, mode)
+}
+
 // StatusResponse represents the status response
 type StatusResponse struct {
 	Status       string `json:"status"`
@@ -103,19 +115,22 @@ func (h *Handlers) Intake(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	intake := modes.NewIntakeMode(nil, h.contractsDir)
-	intake.SetStepValue(req.ProjectName)
-	intake.NextStep()
-	intake.SetStepValue(req.Description)
-	intake.NextStep()
-	intake.SetStepValue(req.TargetUsers)
-	intake.NextStep()
-	intake.SetStepValue(req.CoreFeatures)
-	intake.NextStep()
-	intake.SetStepValue(req.TechnicalConstraints)
-	intake.NextStep()
-	intake.SetStepValue(req.SuccessCriteria)
-	intake.NextStep()
+	provider, settings, err := h.resolveMode(r.Context(), modes.ModeIntake)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	intake := modes.NewIntakeMode(provider, h.contractsDir)
+	intake.SetSettings(settings)
+	intake.SetData(modes.IntakeData{
+		ProjectName:          req.ProjectName,
+		Description:          req.Description,
+		TargetUsers:          req.TargetUsers,
+		CoreFeatures:         req.CoreFeatures,
+		TechnicalConstraints: req.TechnicalConstraints,
+		SuccessCriteria:      req.SuccessCriteria,
+	})
 
 	spec, err := intake.GenerateSpec(context.Background())
 	if err != nil {
@@ -130,9 +145,10 @@ func (h *Handlers) Intake(w http.ResponseWriter, r *http.Request) {
 	}
 
 	jsonResponse(w, map[string]interface{}{
-		"success": true,
-		"spec":    spec,
-		"path":    path,
+		"success":      true,
+		"spec":         spec,
+		"path":         path,
+		"generated_by": intake.GeneratedBy(),
 	})
 }
 
@@ -155,7 +171,14 @@ func (h *Handlers) Review(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	review := modes.NewReviewMode(nil, h.reportsDir)
+	provider, settings, err := h.resolveMode(r.Context(), modes.ModeReview)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	review := modes.NewReviewMode(provider, h.reportsDir)
+	review.SetSettings(settings)
 	review.SetSpecFile(req.SpecFile)
 	review.SetCodePaths(req.CodePaths)
 
@@ -176,6 +199,7 @@ func (h *Handlers) Review(w http.ResponseWriter, r *http.Request) {
 		"compliance_score": result.ComplianceScore,
 		"report":           result.FullReport,
 		"path":             path,
+		"generated_by":     result.GeneratedBy,
 	})
 }
 
@@ -197,7 +221,14 @@ func (h *Handlers) Rescue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rescue := modes.NewRescueMode(nil, h.contractsDir, h.reportsDir)
+	provider, settings, err := h.resolveMode(r.Context(), modes.ModeRescue)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rescue := modes.NewRescueMode(provider, h.contractsDir, h.reportsDir)
+	rescue.SetSettings(settings)
 	rescue.SetCodebasePath(req.CodebasePath)
 
 	result, err := rescue.ScanCodebase(context.Background())
@@ -218,6 +249,7 @@ func (h *Handlers) Rescue(w http.ResponseWriter, r *http.Request) {
 		"spec":          result.InferredSpec,
 		"spec_path":     specPath,
 		"report_path":   reportPath,
+		"generated_by":  result.GeneratedBy,
 	})
 }
 
@@ -240,7 +272,14 @@ func (h *Handlers) ChangeOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	co := modes.NewChangeOrderMode(nil, h.contractsDir)
+	provider, settings, err := h.resolveMode(r.Context(), modes.ModeChangeOrder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	co := modes.NewChangeOrderMode(provider, h.contractsDir)
+	co.SetSettings(settings)
 	co.SetSpecFile(req.SpecFile)
 	co.SetCodebasePath(req.CodebasePath)
 
@@ -257,10 +296,11 @@ func (h *Handlers) ChangeOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	jsonResponse(w, map[string]interface{}{
-		"success": true,
-		"changes": result.Changes,
-		"report":  result.FullReport,
-		"path":    path,
+		"success":      true,
+		"changes":      result.Changes,
+		"report":       result.FullReport,
+		"path":         path,
+		"generated_by": result.GeneratedBy,
 	})
 }
 
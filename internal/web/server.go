@@ -8,6 +8,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/ssdajoker/Code-Factory/internal/modes"
 )
 
 //go:embed static/*
@@ -21,6 +23,7 @@ type Server struct {
 	port         int
 	contractsDir string
 	reportsDir   string
+	resolve      modes.Resolver
 	server       *http.Server
 }
 
@@ -42,12 +45,17 @@ func NewServer(port int, contractsDir, reportsDir string) *Server {
 	}
 }
 
+// SetResolver sets how the modes pick their provider and settings
+func (s *Server) SetResolver(resolve modes.Resolver) {
+	s.resolve = resolve
+}
+
 // Start starts the web server
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
 
 	// API routes
-	h := NewHandlers(s.contractsDir, s.reportsDir)
+	h := NewHandlers(s.contractsDir, s.reportsDir, s.resolve)
 	mux.HandleFunc("/api/status", h.Status)
 	mux.HandleFunc("/api/modes", h.Modes)
 	mux.HandleFunc("/api/intake", h.Intake)
@@ -197,7 +197,7 @@ async function handleReview(e) {
         const json = await resp.json();
         if (json.success) {
             result.innerHTML = `
-                <p class="success">✓ Compliance Score: ${json.compliance_score}/100</p>
+                <p class="success">✓ Compliance Score: ${json.compliance_score === null ? 'not scored' : json.compliance_score + '/100'}</p>
                 <p>Saved to ${json.path}</p>
                 <div class="result">${json.report}</div>
             `;